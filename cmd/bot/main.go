@@ -1,23 +1,15 @@
 package main
 
 import (
-	"encoding/csv"
-	"fmt"
+	"context"
+	"errors"
+	"github.com/LuccChagas/my-chat-app/config"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
 	"github.com/joho/godotenv"
-	"io"
 	"log"
 	"net/http"
-	"os"
-	"strings"
-	"time"
-
-	"github.com/rabbitmq/amqp091-go"
-)
-
-const (
-	stockAPIURLTemplate = "https://stooq.com/q/l/?s=%s&f=sd2t2ohlcv&h&e=csv"
-	requestQueueName    = "mq_stock_code_req"
-	responseQueueName   = "mq_stock_code_res"
+	"os/signal"
+	"syscall"
 )
 
 func init() {
@@ -27,137 +19,51 @@ func init() {
 	}
 }
 
-func ConnRabbit() (*amqp091.Connection, error) {
-	url := fmt.Sprintf("amqp://%s:%s@%s:%s/",
-		os.Getenv("AMQP_USER"),
-		os.Getenv("AMQP_PASS"),
-		os.Getenv("AMQP_HOST"),
-		os.Getenv("AMQP_PORT"))
-
-	conn, err := amqp091.Dial(url)
-	if err != nil {
-		return nil, err
-	}
-
-	return conn, nil
-}
-
-func processStockCmd(stockCode string) (string, error) {
-	url := fmt.Sprintf(stockAPIURLTemplate, stockCode)
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get(url)
-	if err != nil {
-		return "", fmt.Errorf("error calling API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API returned status %d", resp.StatusCode)
-	}
-
-	reader := csv.NewReader(resp.Body)
-	records, err := reader.ReadAll()
-	if err != nil && err != io.EOF {
-		return "", fmt.Errorf("error parsing CSV: %w", err)
-	}
-
-	if len(records) < 2 {
-		return "", fmt.Errorf("incomplete CSV")
-	}
-	data := records[1]
-	if len(data) < 7 {
-		return "", fmt.Errorf("unexpected CSV format")
-	}
-	closePrice := data[6]
-	if closePrice == "N/D" {
-		return fmt.Sprintf("%s quote is not available", strings.ToUpper(stockCode)), nil
-	}
-
-	responseMsg := fmt.Sprintf("%s quote is $%s per share", strings.ToUpper(stockCode), closePrice)
-	return responseMsg, nil
-}
-
 func main() {
-	conn, err := ConnRabbit()
+	db, err := config.ConnDB()
 	if err != nil {
-		return
+		log.Fatalf("Error connecting to the database: %v", err)
 	}
-	defer conn.Close()
+	defer db.Close()
 
-	ch, err := conn.Channel()
+	conn, err := config.ConnRabbit()
 	if err != nil {
-		log.Fatalf("Error opening channel: %v", err)
+		log.Fatalf("Error connecting to RabbitMQ: %v", err)
 	}
-	defer ch.Close()
 
-	reqQueue, err := ch.QueueDeclare(
-		requestQueueName,
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		log.Fatalf("Error declaring request queue: %v", err)
-	}
+	messageBus := bus.NewRabbitBus(conn)
 
-	respQueue, err := ch.QueueDeclare(
-		responseQueueName,
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
+	stockBot, err := config.NewBot(messageBus, db)
 	if err != nil {
-		log.Fatalf("Error declaring response queue: %v", err)
+		log.Fatalf("Error creating bot: %v", err)
 	}
-
-	msgs, err := ch.Consume(
-		reqQueue.Name,
-		"",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		log.Fatalf("Error registering consumer: %v", err)
+	if err = stockBot.Start(); err != nil {
+		log.Fatalf("Error starting bot: %v", err)
 	}
 
-	log.Printf("Bot started. Waiting for messages on queue %q...", reqQueue.Name)
-	forever := make(chan bool)
-
+	server := config.NewBotServer(stockBot, messageBus)
 	go func() {
-		for d := range msgs {
-			stockCode := string(d.Body)
-			log.Printf("Command received: %s", stockCode)
-
-			responseMsg, err := processStockCmd(stockCode)
-			if err != nil {
-				log.Printf("Error processing command - %s: %v", stockCode, err)
-				continue
-			}
-
-			err = ch.Publish(
-				"",
-				respQueue.Name,
-				false,
-				false,
-				amqp091.Publishing{
-					ContentType: "text/plain",
-					Body:        []byte(responseMsg),
-				},
-			)
-			if err != nil {
-				log.Printf("Error publishing response: %v", err)
-				continue
-			}
-			log.Printf("Response published: %s", responseMsg)
+		if err := server.Start(config.BotHTTPAddr()); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Error serving the bot endpoints: %v", err)
 		}
 	}()
 
-	<-forever
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	// stop taking commands, let the ones in flight finish and be acked
+	log.Printf("Shutting down, waiting up to %s for the commands in flight...", config.ShutdownTimeout())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout())
+	defer cancel()
+
+	// readyz fails from now on, the probes are served until the end
+	stockBot.Stop()
+	if err = messageBus.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down the message bus: %v", err)
+	}
+	if err = server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down the bot endpoints: %v", err)
+	}
+	log.Println("Bot stopped")
 }
@@ -16,22 +16,37 @@ func init() {
 
 func main() {
 	hub := websocket.NewHub()
-	go hub.Run()
 
 	db, err := config.ConnDB()
 	if err != nil {
 		return
 	}
 
-	rabbit, err := config.ConnRabbit()
+	messageBus, err := config.NewBus()
 	if err != nil {
-		return
+		log.Fatalf("Error creating message bus: %v", err)
+	}
+
+	// with the in-memory bus nothing outside this process can answer the commands
+	if config.BusDriver() == config.BusDriverMemory {
+		stockBot, err := config.NewBot(messageBus, db)
+		if err != nil {
+			log.Fatalf("Error creating in-process bot: %v", err)
+		}
+		if err = stockBot.Start(); err != nil {
+			log.Fatalf("Error starting in-process bot: %v", err)
+		}
 	}
 
-	app := config.NewApp(db, hub, rabbit)
+	if err = config.StartCluster(hub, messageBus); err != nil {
+		log.Fatalf("Error starting cluster mode: %v", err)
+	}
+
+	app := config.NewApp(db, hub, messageBus)
+	go hub.Run()
 	app.Server.Serve()
 	log.Println("Servidor iniciado...")
 
-	defer rabbit.Close()
+	defer messageBus.Close()
 	defer db.Close()
 }
@@ -3,12 +3,15 @@ package config
 import (
 	"database/sql"
 	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+	"github.com/LuccChagas/my-chat-app/internal/bot"
 	"github.com/LuccChagas/my-chat-app/internal/handlers"
 	"github.com/LuccChagas/my-chat-app/internal/repository"
 	"github.com/LuccChagas/my-chat-app/internal/routers"
 	"github.com/LuccChagas/my-chat-app/internal/services"
 	"github.com/LuccChagas/my-chat-app/internal/websocket"
-	"github.com/rabbitmq/amqp091-go"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+	"log"
+	"time"
 )
 
 type App struct {
@@ -20,13 +23,19 @@ type RepositoryInstance struct {
 }
 
 type ServiceInstance struct {
-	UserService *services.UserService
-	WsService   *services.WsService
+	UserService    *services.UserService
+	MessageService *services.MessageService
+	DirectService  *services.DirectMessageService
+	WsService      *services.WsService
 }
 
 type HandlerInstance struct {
-	UserHandler *handlers.UserHandler
-	WsHandler   *handlers.WsHandler
+	UserHandler     *handlers.UserHandler
+	MessageHandler  *handlers.MessageHandler
+	DirectHandler   *handlers.DirectMessageHandler
+	PresenceHandler *handlers.PresenceHandler
+	HealthHandler   *handlers.HealthHandler
+	WsHandler       *handlers.WsHandler
 }
 
 func newRepositoryInstance(sqlDB *sql.DB) *RepositoryInstance {
@@ -35,32 +44,90 @@ func newRepositoryInstance(sqlDB *sql.DB) *RepositoryInstance {
 	}
 }
 
-func newHandlerInstance(serviceInstance *ServiceInstance, ws *websocket.Hub) *HandlerInstance {
+func newHandlerInstance(serviceInstance *ServiceInstance, ws *websocket.Hub, messageBus bus.Bus) *HandlerInstance {
 	return &HandlerInstance{
-		UserHandler: handlers.NewUserHandler(serviceInstance.UserService),
-		WsHandler:   handlers.NewWsHandler(serviceInstance.WsService, ws),
+		UserHandler:     handlers.NewUserHandler(serviceInstance.UserService),
+		MessageHandler:  handlers.NewMessageHandler(serviceInstance.MessageService, ws),
+		DirectHandler:   handlers.NewDirectMessageHandler(serviceInstance.DirectService),
+		PresenceHandler: handlers.NewPresenceHandler(ws),
+		HealthHandler:   handlers.NewHealthHandler(messageBus),
+		WsHandler:       handlers.NewWsHandler(serviceInstance.WsService, ws),
 	}
 }
 
-func newServiceInstance(repoInstance *RepositoryInstance, rabbit *amqp091.Connection) *ServiceInstance {
+func newServiceInstance(repoInstance *RepositoryInstance, messageBus bus.Bus) *ServiceInstance {
+	messageService := services.NewMessageService(repoInstance.Repository)
+	directService := services.NewDirectMessageService(repoInstance.Repository)
 	return &ServiceInstance{
-		UserService: services.NewUserService(repoInstance.Repository),
-		WsService:   services.NewWsService(repoInstance.Repository, rabbit),
+		UserService:    services.NewUserService(repoInstance.Repository),
+		MessageService: messageService,
+		DirectService:  directService,
+		WsService:      services.NewWsService(repoInstance.Repository, messageService, directService, messageBus),
 	}
 }
 
-func NewApp(db *sql.DB, hub *websocket.Hub, rabbit *amqp091.Connection) *App {
+// botReplyTimeout reads BOT_REPLY_TIMEOUT, in seconds; 0 disables it.
+func botReplyTimeout() time.Duration {
+	return envSeconds("BOT_REPLY_TIMEOUT", services.DefaultBotReplyTimeout)
+}
+
+// botRateLimit reads the <prefix>_BURST commands allowed at once and the
+// <prefix>_INTERVAL seconds between the next ones; 0 disables the limit.
+func botRateLimit(prefix string, def services.RateLimit) services.RateLimit {
+	return services.RateLimit{
+		Burst:    envInt(prefix+"_BURST", def.Burst, 0),
+		Interval: envSeconds(prefix+"_INTERVAL", def.Interval),
+	}
+}
+
+// presenceIdleAfter reads PRESENCE_IDLE_AFTER, in seconds; 0 disables idle
+// tracking.
+func presenceIdleAfter() time.Duration {
+	return envSeconds("PRESENCE_IDLE_AFTER", websocket.DefaultIdleAfter)
+}
+
+// adminNicknames reads the comma separated ADMIN_NICKNAMES.
+func adminNicknames() []string {
+	return envNicknames("ADMIN_NICKNAMES")
+}
+
+// moderatorNicknames reads the comma separated MODERATOR_NICKNAMES.
+func moderatorNicknames() []string {
+	return envNicknames("MODERATOR_NICKNAMES")
+}
+
+// NewApp wires the application. The hub must not be running yet.
+func NewApp(db *sql.DB, hub *websocket.Hub, messageBus bus.Bus) *App {
 
 	repoInstance := newRepositoryInstance(db)
-	serviceInstance := newServiceInstance(repoInstance, rabbit)
-	handlerInstance := newHandlerInstance(serviceInstance, hub)
+	serviceInstance := newServiceInstance(repoInstance, messageBus)
+	handlerInstance := newHandlerInstance(serviceInstance, hub, messageBus)
 
 	server := routers.NewRouter(
 		handlerInstance.UserHandler,
+		handlerInstance.MessageHandler,
+		handlerInstance.DirectHandler,
+		handlerInstance.PresenceHandler,
+		handlerInstance.HealthHandler,
 		handlerInstance.WsHandler,
 	)
 
-	err := serviceInstance.WsService.GetStockResponse(hub)
+	serviceInstance.WsService.SetReplyTimeout(botReplyTimeout())
+	serviceInstance.WsService.SetAdmins(adminNicknames())
+	serviceInstance.MessageService.SetModerators(moderatorNicknames())
+	serviceInstance.WsService.SetBotRateLimits(
+		botRateLimit("BOT_USER_RATE", services.DefaultUserRateLimit),
+		botRateLimit("BOT_ROOM_RATE", services.DefaultRoomRateLimit),
+	)
+	hub.Observer = serviceInstance.WsService.ObserveBroadcast
+	hub.IdleAfter = presenceIdleAfter()
+
+	if err := bot.DeclareTopics(messageBus); err != nil {
+		log.Printf("Error declaring the bot topics: %v", err)
+		return nil
+	}
+
+	err := serviceInstance.WsService.ConsumeBotReplies(hub)
 	if err != nil {
 		return nil
 	}
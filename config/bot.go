@@ -0,0 +1,83 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+	"github.com/LuccChagas/my-chat-app/internal/bot"
+	"github.com/LuccChagas/my-chat-app/internal/handlers"
+	"github.com/LuccChagas/my-chat-app/internal/quotes"
+	"github.com/LuccChagas/my-chat-app/internal/repository"
+	"github.com/LuccChagas/my-chat-app/internal/routers"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+	"github.com/labstack/echo/v4"
+	"os"
+	"time"
+)
+
+const (
+	QuoteProviderStooq = "stooq"
+	QuoteProviderFile  = "file"
+
+	defaultQuoteFixtures = "fixtures/quotes"
+
+	defaultShutdownTimeout = 30 * time.Second
+
+	defaultBotHTTPAddr = ":8081"
+)
+
+// ShutdownTimeout is how long BOT_SHUTDOWN_TIMEOUT lets the in-flight
+// commands finish after a SIGTERM.
+func ShutdownTimeout() time.Duration {
+	return envSeconds("BOT_SHUTDOWN_TIMEOUT", defaultShutdownTimeout)
+}
+
+// NewBot creates the stock bot configured from the environment. Quotes come
+// from the provider selected by QUOTE_PROVIDER and are cached for
+// QUOTE_CACHE_TTL seconds, and BOT_WORKERS commands are handled at once.
+// With a database the price alerts are stored in it and checked every
+// ALERT_POLL_INTERVAL seconds.
+func NewBot(messageBus bus.Bus, sqlDB *sql.DB) (*bot.Bot, error) {
+	provider, err := newQuoteProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	stockBot := bot.New(messageBus, provider, envSeconds("QUOTE_CACHE_TTL", bot.DefaultCacheTTL))
+	stockBot.SetWorkers(envInt("BOT_WORKERS", bot.DefaultWorkers, 1))
+	if sqlDB != nil {
+		stockBot.EnableAlerts(repository.NewRepository(sqlDB, db.New(sqlDB)),
+			envSeconds("ALERT_POLL_INTERVAL", bot.DefaultAlertInterval))
+	}
+	return stockBot, nil
+}
+
+// NewBotServer creates the server of the bot probes and metrics.
+func NewBotServer(stockBot *bot.Bot, messageBus bus.Bus) *echo.Echo {
+	return routers.NewBotRouter(handlers.NewBotHandler(stockBot, messageBus)).Echo()
+}
+
+// BotHTTPAddr is where the bot endpoints listen, BOT_HTTP_ADDR or :8081.
+func BotHTTPAddr() string {
+	if addr := os.Getenv("BOT_HTTP_ADDR"); addr != "" {
+		return addr
+	}
+	return defaultBotHTTPAddr
+}
+
+// newQuoteProvider returns stooq.com by default, or the fixture files found
+// in QUOTE_FIXTURES_DIR with QUOTE_PROVIDER=file.
+func newQuoteProvider() (bot.QuoteProvider, error) {
+	switch provider := os.Getenv("QUOTE_PROVIDER"); provider {
+	case "", QuoteProviderStooq:
+		return quotes.NewStooq(), nil
+	case QuoteProviderFile:
+		dir := os.Getenv("QUOTE_FIXTURES_DIR")
+		if dir == "" {
+			dir = defaultQuoteFixtures
+		}
+		return quotes.NewFiles(dir), nil
+	default:
+		return nil, fmt.Errorf("unknown QUOTE_PROVIDER %q", provider)
+	}
+}
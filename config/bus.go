@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+	"os"
+)
+
+const (
+	BusDriverRabbit = "rabbitmq"
+	BusDriverMemory = "memory"
+)
+
+// BusDriver returns the message bus backend selected by BUS_DRIVER.
+func BusDriver() string {
+	if driver := os.Getenv("BUS_DRIVER"); driver != "" {
+		return driver
+	}
+	return BusDriverRabbit
+}
+
+func NewBus() (bus.Bus, error) {
+	switch BusDriver() {
+	case BusDriverMemory:
+		return bus.NewMemoryBus(), nil
+	case BusDriverRabbit:
+		conn, err := ConnRabbit()
+		if err != nil {
+			return nil, err
+		}
+		return bus.NewRabbitBus(conn), nil
+	default:
+		return nil, fmt.Errorf("unknown BUS_DRIVER %q", BusDriver())
+	}
+}
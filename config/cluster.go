@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"github.com/LuccChagas/my-chat-app/internal/cluster"
+	"github.com/LuccChagas/my-chat-app/internal/websocket"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+	"github.com/google/uuid"
+	"os"
+	"strconv"
+)
+
+// StartCluster relays the hub broadcasts to the other server instances when
+// CLUSTER_MODE is enabled. It must run before the hub is started.
+func StartCluster(hub *websocket.Hub, messageBus bus.Bus) error {
+	enabled, _ := strconv.ParseBool(os.Getenv("CLUSTER_MODE"))
+	if !enabled {
+		return nil
+	}
+
+	id := nodeID()
+	relay := cluster.NewRelay(messageBus, hub, id)
+	if err := relay.Start(); err != nil {
+		return err
+	}
+	hub.Relay = relay
+	hub.NodeID = id
+
+	return nil
+}
+
+func nodeID() string {
+	if id := os.Getenv("NODE_ID"); id != "" {
+		return id
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "node"
+	}
+	return fmt.Sprintf("%s-%s", hostname, uuid.NewString()[:8])
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envSeconds reads a duration given in seconds, falling back to def when the
+// variable is empty or invalid.
+func envSeconds(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		log.Printf("Invalid %s %q, using %s", name, value, def)
+		return def
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// envInt reads a number of at least minimum, falling back to def when the
+// variable is empty or invalid.
+func envInt(name string, def, minimum int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < minimum {
+		log.Printf("Invalid %s %q, using %d", name, value, def)
+		return def
+	}
+	return n
+}
+
+// envNicknames reads a comma separated list of nicknames.
+func envNicknames(name string) []string {
+	var nicknames []string
+	for _, nickname := range strings.Split(os.Getenv(name), ",") {
+		if nickname = strings.TrimSpace(nickname); nickname != "" {
+			nicknames = append(nicknames, nickname)
+		}
+	}
+	return nicknames
+}
@@ -2,18 +2,20 @@ package config
 
 import (
 	"fmt"
-	"github.com/rabbitmq/amqp091-go"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
 	"os"
 )
 
-func ConnRabbit() (*amqp091.Connection, error) {
+// ConnRabbit dials RabbitMQ; the connection reconnects by itself after a
+// broker restart.
+func ConnRabbit() (*bus.RabbitConnection, error) {
 	url := fmt.Sprintf("amqp://%s:%s@%s:%s/",
 		os.Getenv("AMQP_USER"),
 		os.Getenv("AMQP_PASS"),
 		os.Getenv("AMQP_HOST"),
 		os.Getenv("AMQP_PORT"))
 
-	conn, err := amqp091.Dial(url)
+	conn, err := bus.DialRabbit(url)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS messages CASCADE;
\ No newline at end of file
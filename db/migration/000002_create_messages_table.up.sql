@@ -0,0 +1,9 @@
+CREATE TABLE "messages" (
+                         "id" uuid PRIMARY KEY,
+                         "room" varchar NOT NULL,
+                         "author" varchar NOT NULL,
+                         "content" text NOT NULL,
+                         "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX "messages_room_created_at_idx" ON "messages" ("room", "created_at" DESC, "id" DESC);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS stock_alerts CASCADE;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE "stock_alerts" (
+                             "id" bigserial PRIMARY KEY,
+                             "owner" varchar NOT NULL,
+                             "room" varchar NOT NULL,
+                             "symbol" varchar NOT NULL,
+                             "operator" varchar(2) NOT NULL CHECK ("operator" IN ('>', '>=', '<', '<=')),
+                             "threshold" double precision NOT NULL,
+                             "created_at" timestamptz NOT NULL DEFAULT (now()),
+                             "triggered_at" timestamptz,
+                             "armed" boolean NOT NULL DEFAULT true
+);
+
+CREATE INDEX "stock_alerts_owner_idx" ON "stock_alerts" ("owner");
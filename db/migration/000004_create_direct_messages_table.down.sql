@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS direct_messages CASCADE;
+DROP TABLE IF EXISTS dm_channels CASCADE;
\ No newline at end of file
@@ -0,0 +1,20 @@
+CREATE TABLE "dm_channels" (
+                            "id" uuid PRIMARY KEY,
+                            "user_a" varchar NOT NULL,
+                            "user_b" varchar NOT NULL,
+                            "created_at" timestamptz NOT NULL DEFAULT (now()),
+                            CHECK ("user_a" < "user_b"),
+                            UNIQUE ("user_a", "user_b")
+);
+
+CREATE INDEX "dm_channels_user_b_idx" ON "dm_channels" ("user_b");
+
+CREATE TABLE "direct_messages" (
+                                "id" uuid PRIMARY KEY,
+                                "channel_id" uuid NOT NULL REFERENCES "dm_channels" ("id") ON DELETE CASCADE,
+                                "author" varchar NOT NULL,
+                                "content" text NOT NULL,
+                                "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX "direct_messages_channel_created_at_idx" ON "direct_messages" ("channel_id", "created_at" DESC, "id" DESC);
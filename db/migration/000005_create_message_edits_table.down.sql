@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS message_edits CASCADE;
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS edited_at,
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS deleted_by;
\ No newline at end of file
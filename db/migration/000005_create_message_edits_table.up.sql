@@ -0,0 +1,14 @@
+ALTER TABLE "messages"
+    ADD COLUMN "edited_at" timestamptz,
+    ADD COLUMN "deleted_at" timestamptz,
+    ADD COLUMN "deleted_by" varchar;
+
+CREATE TABLE "message_edits" (
+                              "id" bigserial PRIMARY KEY,
+                              "message_id" uuid NOT NULL REFERENCES "messages" ("id") ON DELETE CASCADE,
+                              "content" text NOT NULL,
+                              "edited_by" varchar NOT NULL,
+                              "edited_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX "message_edits_message_id_idx" ON "message_edits" ("message_id", "edited_at");
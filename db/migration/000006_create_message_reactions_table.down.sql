@@ -0,0 +1 @@
+DROP TABLE IF EXISTS message_reactions CASCADE;
\ No newline at end of file
@@ -0,0 +1,7 @@
+CREATE TABLE "message_reactions" (
+                                  "message_id" uuid NOT NULL REFERENCES "messages" ("id") ON DELETE CASCADE,
+                                  "nickname" varchar NOT NULL,
+                                  "emoji" varchar NOT NULL,
+                                  "created_at" timestamptz NOT NULL DEFAULT (now()),
+                                  PRIMARY KEY ("message_id", "nickname", "emoji")
+);
@@ -0,0 +1,42 @@
+-- name: UpsertDMChannel :one
+INSERT INTO dm_channels
+(id, user_a, user_b, created_at)
+VALUES($1, $2, $3, now())
+ON CONFLICT (user_a, user_b) DO UPDATE SET user_a = EXCLUDED.user_a
+RETURNING *;
+
+-- name: GetDMChannel :one
+SELECT * FROM dm_channels
+WHERE dm_channels.user_a = $1 AND dm_channels.user_b = $2;
+
+-- name: ListDMChannels :many
+SELECT c.id, c.user_a, c.user_b, c.created_at,
+       last.id AS last_id, last.author AS last_author, last.content AS last_content, last.created_at AS last_created_at
+FROM dm_channels c
+LEFT JOIN LATERAL (
+    SELECT direct_messages.id, direct_messages.author, direct_messages.content, direct_messages.created_at FROM direct_messages
+    WHERE direct_messages.channel_id = c.id
+    ORDER BY direct_messages.created_at DESC, direct_messages.id DESC
+    LIMIT 1
+) last ON true
+WHERE c.user_a = sqlc.arg(nickname) OR c.user_b = sqlc.arg(nickname)
+ORDER BY COALESCE(last.created_at, c.created_at) DESC;
+
+-- name: CreateDirectMessage :one
+INSERT INTO direct_messages
+(id, channel_id, author, content, created_at)
+VALUES($1, $2, $3, $4, now())
+RETURNING *;
+
+-- name: GetRecentDirectMessages :many
+SELECT * FROM direct_messages
+WHERE direct_messages.channel_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
+
+-- name: GetDirectMessagesBefore :many
+SELECT * FROM direct_messages
+WHERE direct_messages.channel_id = sqlc.arg(channel_id)
+  AND (direct_messages.created_at, direct_messages.id) < (sqlc.arg(created_at)::timestamptz, sqlc.arg(id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit);
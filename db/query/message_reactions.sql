@@ -0,0 +1,21 @@
+-- name: AddReaction :execrows
+INSERT INTO message_reactions
+(message_id, nickname, emoji)
+VALUES($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveReaction :execrows
+DELETE FROM message_reactions
+WHERE message_id = $1 AND nickname = $2 AND emoji = $3;
+
+-- name: CountReactions :one
+SELECT count(*) FROM message_reactions
+WHERE message_id = $1 AND emoji = $2;
+
+-- name: ListReactionCounts :many
+SELECT message_id, emoji, count(*) AS count,
+       bool_or(nickname = sqlc.arg(nickname))::boolean AS reacted
+FROM message_reactions
+WHERE message_id = ANY(sqlc.arg(message_ids)::uuid[])
+GROUP BY message_id, emoji
+ORDER BY message_id, min(created_at), emoji;
@@ -0,0 +1,57 @@
+-- name: CreateMessage :one
+INSERT INTO messages
+(id, room, author, content, created_at)
+VALUES($1, $2, $3, $4, now())
+RETURNING *;
+
+-- name: GetRecentMessages :many
+SELECT * FROM messages
+WHERE messages.room = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
+
+-- name: GetMessagesBefore :many
+SELECT * FROM messages
+WHERE messages.room = sqlc.arg(room)
+  AND (messages.created_at, messages.id) < (sqlc.arg(created_at)::timestamptz, sqlc.arg(id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: GetMessage :one
+SELECT * FROM messages
+WHERE id = $1;
+
+-- name: EditMessage :one
+WITH previous AS (
+    INSERT INTO message_edits (message_id, content, edited_by)
+    SELECT messages.id, messages.content, sqlc.arg(edited_by)
+    FROM messages
+    WHERE messages.id = sqlc.arg(id) AND messages.deleted_at IS NULL
+    FOR UPDATE
+    RETURNING message_id
+)
+UPDATE messages
+SET content = sqlc.arg(content), edited_at = now()
+FROM previous
+WHERE messages.id = previous.message_id
+RETURNING messages.*;
+
+-- name: DeleteMessage :one
+WITH previous AS (
+    INSERT INTO message_edits (message_id, content, edited_by)
+    SELECT messages.id, messages.content, sqlc.arg(deleted_by)
+    FROM messages
+    WHERE messages.id = sqlc.arg(id) AND messages.deleted_at IS NULL
+    FOR UPDATE
+    RETURNING message_id
+)
+UPDATE messages
+SET content = '', deleted_at = now(), deleted_by = sqlc.arg(deleted_by)
+FROM previous
+WHERE messages.id = previous.message_id
+RETURNING messages.*;
+
+-- name: ListMessageEdits :many
+SELECT * FROM message_edits
+WHERE message_id = $1
+ORDER BY edited_at, id;
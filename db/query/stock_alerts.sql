@@ -0,0 +1,32 @@
+-- name: CreateStockAlert :one
+INSERT INTO stock_alerts
+(owner, room, symbol, operator, threshold, armed)
+VALUES($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: CountStockAlertsByOwner :one
+SELECT count(*) FROM stock_alerts
+WHERE stock_alerts.owner = $1;
+
+-- name: ListStockAlertsByOwner :many
+SELECT * FROM stock_alerts
+WHERE stock_alerts.owner = $1
+ORDER BY id;
+
+-- name: ListStockAlerts :many
+SELECT * FROM stock_alerts
+ORDER BY symbol, id;
+
+-- name: DeleteStockAlert :execrows
+DELETE FROM stock_alerts
+WHERE id = $1 AND owner = $2;
+
+-- name: TriggerStockAlert :execrows
+UPDATE stock_alerts
+SET armed = false, triggered_at = now()
+WHERE id = $1 AND armed;
+
+-- name: RearmStockAlert :exec
+UPDATE stock_alerts
+SET armed = true
+WHERE id = $1;
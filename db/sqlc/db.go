@@ -24,33 +24,180 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.addReactionStmt, err = db.PrepareContext(ctx, addReaction); err != nil {
+		return nil, fmt.Errorf("error preparing query AddReaction: %w", err)
+	}
+	if q.countReactionsStmt, err = db.PrepareContext(ctx, countReactions); err != nil {
+		return nil, fmt.Errorf("error preparing query CountReactions: %w", err)
+	}
+	if q.countStockAlertsByOwnerStmt, err = db.PrepareContext(ctx, countStockAlertsByOwner); err != nil {
+		return nil, fmt.Errorf("error preparing query CountStockAlertsByOwner: %w", err)
+	}
+	if q.createDirectMessageStmt, err = db.PrepareContext(ctx, createDirectMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateDirectMessage: %w", err)
+	}
+	if q.createMessageStmt, err = db.PrepareContext(ctx, createMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateMessage: %w", err)
+	}
+	if q.createStockAlertStmt, err = db.PrepareContext(ctx, createStockAlert); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateStockAlert: %w", err)
+	}
 	if q.createUsersStmt, err = db.PrepareContext(ctx, createUsers); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateUsers: %w", err)
 	}
+	if q.deleteMessageStmt, err = db.PrepareContext(ctx, deleteMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteMessage: %w", err)
+	}
+	if q.deleteStockAlertStmt, err = db.PrepareContext(ctx, deleteStockAlert); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteStockAlert: %w", err)
+	}
+	if q.editMessageStmt, err = db.PrepareContext(ctx, editMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query EditMessage: %w", err)
+	}
 	if q.getAllUsersStmt, err = db.PrepareContext(ctx, getAllUsers); err != nil {
 		return nil, fmt.Errorf("error preparing query GetAllUsers: %w", err)
 	}
+	if q.getDMChannelStmt, err = db.PrepareContext(ctx, getDMChannel); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDMChannel: %w", err)
+	}
+	if q.getDirectMessagesBeforeStmt, err = db.PrepareContext(ctx, getDirectMessagesBefore); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDirectMessagesBefore: %w", err)
+	}
+	if q.getMessageStmt, err = db.PrepareContext(ctx, getMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessage: %w", err)
+	}
+	if q.getMessagesBeforeStmt, err = db.PrepareContext(ctx, getMessagesBefore); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessagesBefore: %w", err)
+	}
+	if q.getRecentDirectMessagesStmt, err = db.PrepareContext(ctx, getRecentDirectMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query GetRecentDirectMessages: %w", err)
+	}
+	if q.getRecentMessagesStmt, err = db.PrepareContext(ctx, getRecentMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query GetRecentMessages: %w", err)
+	}
 	if q.getUserStmt, err = db.PrepareContext(ctx, getUser); err != nil {
 		return nil, fmt.Errorf("error preparing query GetUser: %w", err)
 	}
 	if q.getUserByNicknameStmt, err = db.PrepareContext(ctx, getUserByNickname); err != nil {
 		return nil, fmt.Errorf("error preparing query GetUserByNickname: %w", err)
 	}
+	if q.listDMChannelsStmt, err = db.PrepareContext(ctx, listDMChannels); err != nil {
+		return nil, fmt.Errorf("error preparing query ListDMChannels: %w", err)
+	}
+	if q.listMessageEditsStmt, err = db.PrepareContext(ctx, listMessageEdits); err != nil {
+		return nil, fmt.Errorf("error preparing query ListMessageEdits: %w", err)
+	}
+	if q.listReactionCountsStmt, err = db.PrepareContext(ctx, listReactionCounts); err != nil {
+		return nil, fmt.Errorf("error preparing query ListReactionCounts: %w", err)
+	}
+	if q.listStockAlertsStmt, err = db.PrepareContext(ctx, listStockAlerts); err != nil {
+		return nil, fmt.Errorf("error preparing query ListStockAlerts: %w", err)
+	}
+	if q.listStockAlertsByOwnerStmt, err = db.PrepareContext(ctx, listStockAlertsByOwner); err != nil {
+		return nil, fmt.Errorf("error preparing query ListStockAlertsByOwner: %w", err)
+	}
+	if q.rearmStockAlertStmt, err = db.PrepareContext(ctx, rearmStockAlert); err != nil {
+		return nil, fmt.Errorf("error preparing query RearmStockAlert: %w", err)
+	}
+	if q.removeReactionStmt, err = db.PrepareContext(ctx, removeReaction); err != nil {
+		return nil, fmt.Errorf("error preparing query RemoveReaction: %w", err)
+	}
+	if q.triggerStockAlertStmt, err = db.PrepareContext(ctx, triggerStockAlert); err != nil {
+		return nil, fmt.Errorf("error preparing query TriggerStockAlert: %w", err)
+	}
+	if q.upsertDMChannelStmt, err = db.PrepareContext(ctx, upsertDMChannel); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertDMChannel: %w", err)
+	}
 	return &q, nil
 }
 
 func (q *Queries) Close() error {
 	var err error
+	if q.addReactionStmt != nil {
+		if cerr := q.addReactionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addReactionStmt: %w", cerr)
+		}
+	}
+	if q.countReactionsStmt != nil {
+		if cerr := q.countReactionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countReactionsStmt: %w", cerr)
+		}
+	}
+	if q.countStockAlertsByOwnerStmt != nil {
+		if cerr := q.countStockAlertsByOwnerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countStockAlertsByOwnerStmt: %w", cerr)
+		}
+	}
+	if q.createDirectMessageStmt != nil {
+		if cerr := q.createDirectMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createDirectMessageStmt: %w", cerr)
+		}
+	}
+	if q.createMessageStmt != nil {
+		if cerr := q.createMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createMessageStmt: %w", cerr)
+		}
+	}
+	if q.createStockAlertStmt != nil {
+		if cerr := q.createStockAlertStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createStockAlertStmt: %w", cerr)
+		}
+	}
 	if q.createUsersStmt != nil {
 		if cerr := q.createUsersStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createUsersStmt: %w", cerr)
 		}
 	}
+	if q.deleteMessageStmt != nil {
+		if cerr := q.deleteMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteMessageStmt: %w", cerr)
+		}
+	}
+	if q.deleteStockAlertStmt != nil {
+		if cerr := q.deleteStockAlertStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteStockAlertStmt: %w", cerr)
+		}
+	}
+	if q.editMessageStmt != nil {
+		if cerr := q.editMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing editMessageStmt: %w", cerr)
+		}
+	}
 	if q.getAllUsersStmt != nil {
 		if cerr := q.getAllUsersStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getAllUsersStmt: %w", cerr)
 		}
 	}
+	if q.getDMChannelStmt != nil {
+		if cerr := q.getDMChannelStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDMChannelStmt: %w", cerr)
+		}
+	}
+	if q.getDirectMessagesBeforeStmt != nil {
+		if cerr := q.getDirectMessagesBeforeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDirectMessagesBeforeStmt: %w", cerr)
+		}
+	}
+	if q.getMessageStmt != nil {
+		if cerr := q.getMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessageStmt: %w", cerr)
+		}
+	}
+	if q.getMessagesBeforeStmt != nil {
+		if cerr := q.getMessagesBeforeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessagesBeforeStmt: %w", cerr)
+		}
+	}
+	if q.getRecentDirectMessagesStmt != nil {
+		if cerr := q.getRecentDirectMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getRecentDirectMessagesStmt: %w", cerr)
+		}
+	}
+	if q.getRecentMessagesStmt != nil {
+		if cerr := q.getRecentMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getRecentMessagesStmt: %w", cerr)
+		}
+	}
 	if q.getUserStmt != nil {
 		if cerr := q.getUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getUserStmt: %w", cerr)
@@ -61,6 +208,51 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getUserByNicknameStmt: %w", cerr)
 		}
 	}
+	if q.listDMChannelsStmt != nil {
+		if cerr := q.listDMChannelsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listDMChannelsStmt: %w", cerr)
+		}
+	}
+	if q.listMessageEditsStmt != nil {
+		if cerr := q.listMessageEditsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listMessageEditsStmt: %w", cerr)
+		}
+	}
+	if q.listReactionCountsStmt != nil {
+		if cerr := q.listReactionCountsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listReactionCountsStmt: %w", cerr)
+		}
+	}
+	if q.listStockAlertsStmt != nil {
+		if cerr := q.listStockAlertsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listStockAlertsStmt: %w", cerr)
+		}
+	}
+	if q.listStockAlertsByOwnerStmt != nil {
+		if cerr := q.listStockAlertsByOwnerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listStockAlertsByOwnerStmt: %w", cerr)
+		}
+	}
+	if q.rearmStockAlertStmt != nil {
+		if cerr := q.rearmStockAlertStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing rearmStockAlertStmt: %w", cerr)
+		}
+	}
+	if q.removeReactionStmt != nil {
+		if cerr := q.removeReactionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing removeReactionStmt: %w", cerr)
+		}
+	}
+	if q.triggerStockAlertStmt != nil {
+		if cerr := q.triggerStockAlertStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing triggerStockAlertStmt: %w", cerr)
+		}
+	}
+	if q.upsertDMChannelStmt != nil {
+		if cerr := q.upsertDMChannelStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertDMChannelStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -98,21 +290,69 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                    DBTX
-	tx                    *sql.Tx
-	createUsersStmt       *sql.Stmt
-	getAllUsersStmt       *sql.Stmt
-	getUserStmt           *sql.Stmt
-	getUserByNicknameStmt *sql.Stmt
+	db                          DBTX
+	tx                          *sql.Tx
+	addReactionStmt             *sql.Stmt
+	countReactionsStmt          *sql.Stmt
+	countStockAlertsByOwnerStmt *sql.Stmt
+	createDirectMessageStmt     *sql.Stmt
+	createMessageStmt           *sql.Stmt
+	createStockAlertStmt        *sql.Stmt
+	createUsersStmt             *sql.Stmt
+	deleteMessageStmt           *sql.Stmt
+	deleteStockAlertStmt        *sql.Stmt
+	editMessageStmt             *sql.Stmt
+	getAllUsersStmt             *sql.Stmt
+	getDMChannelStmt            *sql.Stmt
+	getDirectMessagesBeforeStmt *sql.Stmt
+	getMessageStmt              *sql.Stmt
+	getMessagesBeforeStmt       *sql.Stmt
+	getRecentDirectMessagesStmt *sql.Stmt
+	getRecentMessagesStmt       *sql.Stmt
+	getUserStmt                 *sql.Stmt
+	getUserByNicknameStmt       *sql.Stmt
+	listDMChannelsStmt          *sql.Stmt
+	listMessageEditsStmt        *sql.Stmt
+	listReactionCountsStmt      *sql.Stmt
+	listStockAlertsStmt         *sql.Stmt
+	listStockAlertsByOwnerStmt  *sql.Stmt
+	rearmStockAlertStmt         *sql.Stmt
+	removeReactionStmt          *sql.Stmt
+	triggerStockAlertStmt       *sql.Stmt
+	upsertDMChannelStmt         *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                    tx,
-		tx:                    tx,
-		createUsersStmt:       q.createUsersStmt,
-		getAllUsersStmt:       q.getAllUsersStmt,
-		getUserStmt:           q.getUserStmt,
-		getUserByNicknameStmt: q.getUserByNicknameStmt,
+		db:                          tx,
+		tx:                          tx,
+		addReactionStmt:             q.addReactionStmt,
+		countReactionsStmt:          q.countReactionsStmt,
+		countStockAlertsByOwnerStmt: q.countStockAlertsByOwnerStmt,
+		createDirectMessageStmt:     q.createDirectMessageStmt,
+		createMessageStmt:           q.createMessageStmt,
+		createStockAlertStmt:        q.createStockAlertStmt,
+		createUsersStmt:             q.createUsersStmt,
+		deleteMessageStmt:           q.deleteMessageStmt,
+		deleteStockAlertStmt:        q.deleteStockAlertStmt,
+		editMessageStmt:             q.editMessageStmt,
+		getAllUsersStmt:             q.getAllUsersStmt,
+		getDMChannelStmt:            q.getDMChannelStmt,
+		getDirectMessagesBeforeStmt: q.getDirectMessagesBeforeStmt,
+		getMessageStmt:              q.getMessageStmt,
+		getMessagesBeforeStmt:       q.getMessagesBeforeStmt,
+		getRecentDirectMessagesStmt: q.getRecentDirectMessagesStmt,
+		getRecentMessagesStmt:       q.getRecentMessagesStmt,
+		getUserStmt:                 q.getUserStmt,
+		getUserByNicknameStmt:       q.getUserByNicknameStmt,
+		listDMChannelsStmt:          q.listDMChannelsStmt,
+		listMessageEditsStmt:        q.listMessageEditsStmt,
+		listReactionCountsStmt:      q.listReactionCountsStmt,
+		listStockAlertsStmt:         q.listStockAlertsStmt,
+		listStockAlertsByOwnerStmt:  q.listStockAlertsByOwnerStmt,
+		rearmStockAlertStmt:         q.rearmStockAlertStmt,
+		removeReactionStmt:          q.removeReactionStmt,
+		triggerStockAlertStmt:       q.triggerStockAlertStmt,
+		upsertDMChannelStmt:         q.upsertDMChannelStmt,
 	}
 }
@@ -11,6 +11,59 @@ import (
 	"github.com/google/uuid"
 )
 
+type DirectMessage struct {
+	ID        uuid.UUID `json:"id"`
+	ChannelID uuid.UUID `json:"channel_id"`
+	Author    string    `json:"author"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DmChannel struct {
+	ID        uuid.UUID `json:"id"`
+	UserA     string    `json:"user_a"`
+	UserB     string    `json:"user_b"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Message struct {
+	ID        uuid.UUID      `json:"id"`
+	Room      string         `json:"room"`
+	Author    string         `json:"author"`
+	Content   string         `json:"content"`
+	CreatedAt time.Time      `json:"created_at"`
+	EditedAt  sql.NullTime   `json:"edited_at"`
+	DeletedAt sql.NullTime   `json:"deleted_at"`
+	DeletedBy sql.NullString `json:"deleted_by"`
+}
+
+type MessageEdit struct {
+	ID        int64     `json:"id"`
+	MessageID uuid.UUID `json:"message_id"`
+	Content   string    `json:"content"`
+	EditedBy  string    `json:"edited_by"`
+	EditedAt  time.Time `json:"edited_at"`
+}
+
+type MessageReaction struct {
+	MessageID uuid.UUID `json:"message_id"`
+	Nickname  string    `json:"nickname"`
+	Emoji     string    `json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type StockAlert struct {
+	ID          int64        `json:"id"`
+	Owner       string       `json:"owner"`
+	Room        string       `json:"room"`
+	Symbol      string       `json:"symbol"`
+	Operator    string       `json:"operator"`
+	Threshold   float64      `json:"threshold"`
+	CreatedAt   time.Time    `json:"created_at"`
+	TriggeredAt sql.NullTime `json:"triggered_at"`
+	Armed       bool         `json:"armed"`
+}
+
 type User struct {
 	ID        uuid.UUID    `json:"id"`
 	Password  string       `json:"password"`
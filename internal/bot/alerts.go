@@ -0,0 +1,236 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	// DefaultAlertInterval is how often the alert rules are checked.
+	DefaultAlertInterval = time.Minute
+
+	maxAlertsPerUser = 20
+)
+
+var alertPattern = regexp.MustCompile(`^([a-z0-9^][a-z0-9._^-]*)(>=|<=|>|<)([0-9]+(?:\.[0-9]+)?)$`)
+
+// AlertStore keeps the alert rules created with /watch. It is implemented
+// by repository.Repository.
+type AlertStore interface {
+	CreateStockAlert(ctx context.Context, arg db.CreateStockAlertParams) (db.StockAlert, error)
+	CountStockAlertsByOwner(ctx context.Context, owner string) (int64, error)
+	ListStockAlertsByOwner(ctx context.Context, owner string) ([]db.StockAlert, error)
+	ListStockAlerts(ctx context.Context) ([]db.StockAlert, error)
+	DeleteStockAlert(ctx context.Context, arg db.DeleteStockAlertParams) (int64, error)
+	TriggerStockAlert(ctx context.Context, id int64) (int64, error)
+	RearmStockAlert(ctx context.Context, id int64) error
+}
+
+// EnableAlerts turns on the alert commands and checks the rules every
+// interval once the bot is started.
+func (b *Bot) EnableAlerts(store AlertStore, interval time.Duration) {
+	b.alerts = store
+	b.alertInterval = interval
+}
+
+// watch answers "/watch aapl.us > 200".
+func (b *Bot) watch(ctx context.Context, req Request) (Result, error) {
+	if b.alerts == nil {
+		return Result{}, errAlertsUnavailable
+	}
+
+	match := alertPattern.FindStringSubmatch(strings.ToLower(strings.Join(req.Args, "")))
+	if match == nil {
+		return Result{}, newCommandError(CodeInvalidArguments, "usage: /watch <stock_code> <op> <price>, e.g. /watch aapl.us > 200", nil)
+	}
+	symbol, operator := match[1], match[2]
+	threshold, _ := strconv.ParseFloat(match[3], 64)
+
+	count, err := b.alerts.CountStockAlertsByOwner(ctx, req.Requester)
+	if err != nil {
+		return Result{}, err
+	}
+	if count >= maxAlertsPerUser {
+		return Result{}, newCommandError(CodeInvalidArguments,
+			fmt.Sprintf("You already have %d alerts, remove one with /unwatch <id>", count), nil)
+	}
+
+	// also checks that the symbol exists
+	quote, _, err := b.quotes.get(ctx, symbol)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// a price already past the threshold is not a crossing, the alert waits
+	// for it to go back first
+	holds := alertHolds(db.StockAlert{Operator: operator, Threshold: threshold}, quote.Close)
+	alert, err := b.alerts.CreateStockAlert(ctx, db.CreateStockAlertParams{
+		Owner:     req.Requester,
+		Room:      req.Room,
+		Symbol:    symbol,
+		Operator:  operator,
+		Threshold: threshold,
+		Armed:     !holds,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	text := fmt.Sprintf("Alert %s set, %s is now $%.2f", formatAlert(alert), quote.Symbol, quote.Close)
+	if !alert.Armed {
+		text += ", it fires once the price went back and crosses again"
+	}
+	return Result{Text: text}, nil
+}
+
+func (b *Bot) watchlist(ctx context.Context, req Request) (Result, error) {
+	if b.alerts == nil {
+		return Result{}, errAlertsUnavailable
+	}
+
+	alerts, err := b.alerts.ListStockAlertsByOwner(ctx, req.Requester)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(alerts) == 0 {
+		return Result{Text: "You have no alerts, add one with /watch aapl.us > 200"}, nil
+	}
+
+	lines := make([]string, len(alerts))
+	for i, alert := range alerts {
+		lines[i] = formatAlert(alert)
+		switch {
+		case alert.Armed:
+		case alert.TriggeredAt.Valid:
+			lines[i] += " (triggered)"
+		default:
+			lines[i] += " (waiting for the price to go back)"
+		}
+	}
+	return Result{Text: "Your alerts:\n" + strings.Join(lines, "\n")}, nil
+}
+
+func (b *Bot) unwatch(ctx context.Context, req Request) (Result, error) {
+	if b.alerts == nil {
+		return Result{}, errAlertsUnavailable
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(req.Args[0], "#"), 10, 64)
+	if err != nil {
+		return Result{}, newCommandError(CodeInvalidArguments, "usage: /unwatch <id>, the ids are listed by /watchlist", nil)
+	}
+
+	deleted, err := b.alerts.DeleteStockAlert(ctx, db.DeleteStockAlertParams{ID: id, Owner: req.Requester})
+	if err != nil {
+		return Result{}, err
+	}
+	if deleted == 0 {
+		return Result{}, newCommandError(CodeNotFound, fmt.Sprintf("You have no alert #%d", id), nil)
+	}
+	return Result{Text: fmt.Sprintf("Alert #%d removed", id)}, nil
+}
+
+// watchAlerts checks the alert rules every interval until ctx is done.
+func (b *Bot) watchAlerts(ctx context.Context) {
+	ticker := time.NewTicker(b.alertInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.checkAlerts(ctx)
+		}
+	}
+}
+
+// checkAlerts notifies the owners of the armed rules whose threshold was
+// crossed and rearms the rules that no longer hold, so they fire again on
+// the next crossing.
+func (b *Bot) checkAlerts(ctx context.Context) {
+	alerts, err := b.alerts.ListStockAlerts(ctx)
+	if err != nil {
+		log.Printf("Error listing the stock alerts: %v", err)
+		return
+	}
+
+	bySymbol := make(map[string][]db.StockAlert)
+	for _, alert := range alerts {
+		bySymbol[alert.Symbol] = append(bySymbol[alert.Symbol], alert)
+	}
+
+	for symbol, rules := range bySymbol {
+		quote, stale, err := b.quotes.get(ctx, symbol)
+		if err != nil || stale {
+			// an old price must not fire alerts
+			log.Printf("Skipping the alerts of %s, no fresh quote: %v", symbol, err)
+			continue
+		}
+
+		for _, alert := range rules {
+			crossed := alertHolds(alert, quote.Close)
+			switch {
+			case crossed && alert.Armed:
+				b.notifyAlert(ctx, alert, QuoteCard{Quote: quote})
+			case !crossed && !alert.Armed:
+				if err := b.alerts.RearmStockAlert(ctx, alert.ID); err != nil {
+					log.Printf("Error rearming alert #%d: %v", alert.ID, err)
+				}
+			}
+		}
+	}
+}
+
+// notifyAlert sends the owner a private notification. The alert is claimed,
+// which disarms it, first so that several bot instances do not notify twice.
+func (b *Bot) notifyAlert(ctx context.Context, alert db.StockAlert, card QuoteCard) {
+	claimed, err := b.alerts.TriggerStockAlert(ctx, alert.ID)
+	if err != nil || claimed == 0 {
+		return
+	}
+
+	err = b.reply(ctx, ResponseTopic, Reply{
+		ID:        uuid.NewString(),
+		Room:      alert.Room,
+		Requester: alert.Owner,
+		Private:   true,
+		Text:      fmt.Sprintf("Alert %s crossed: %s", formatAlert(alert), formatQuote(card)),
+		Quotes:    []QuoteCard{card},
+	})
+	if err != nil {
+		log.Printf("Error notifying alert #%d: %v", alert.ID, err)
+		// try again on the next check
+		if err := b.alerts.RearmStockAlert(ctx, alert.ID); err != nil {
+			log.Printf("Error rearming alert #%d: %v", alert.ID, err)
+		}
+	}
+}
+
+func alertHolds(alert db.StockAlert, price float64) bool {
+	switch alert.Operator {
+	case ">":
+		return price > alert.Threshold
+	case ">=":
+		return price >= alert.Threshold
+	case "<":
+		return price < alert.Threshold
+	case "<=":
+		return price <= alert.Threshold
+	default:
+		return false
+	}
+}
+
+// formatAlert renders an alert as "#3 AAPL.US > $200.00".
+func formatAlert(alert db.StockAlert) string {
+	return fmt.Sprintf("#%d %s %s $%.2f", alert.ID, strings.ToUpper(alert.Symbol), alert.Operator, alert.Threshold)
+}
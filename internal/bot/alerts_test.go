@@ -0,0 +1,127 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch_Arguments(t *testing.T) {
+	provider := NewFakeProvider()
+	provider.SetPrice("aapl.us", 150)
+	provider.SetPrice("^spx", 5000)
+	store := &FakeAlertStore{}
+	h := startAlertBot(t, provider, store, time.Hour)
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantAlert string
+	}{
+		{name: "spaced", args: []string{"aapl.us", ">", "200"}, wantAlert: "AAPL.US > $200.00"},
+		{name: "compact", args: []string{"aapl.us>200"}, wantAlert: "AAPL.US > $200.00"},
+		{name: "upper case and decimals", args: []string{"AAPL.US", ">=", "199.5"}, wantAlert: "AAPL.US >= $199.50"},
+		{name: "index", args: []string{"^spx", "<", "4000"}, wantAlert: "^SPX < $4000.00"},
+		{name: "unknown operator", args: []string{"aapl.us", "=>", "200"}},
+		{name: "missing price", args: []string{"aapl.us", ">"}},
+		{name: "missing operator", args: []string{"aapl.us", "200"}},
+		{name: "negative price", args: []string{"aapl.us", ">", "-3"}},
+		{name: "bad symbol", args: []string{".aapl", ">", "200"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := h.send(t, "alice", "watch", tt.args...)
+			if tt.wantAlert == "" {
+				assertReplyError(t, reply, CodeInvalidArguments)
+				return
+			}
+			assert.Nil(t, reply.Error)
+			assert.Contains(t, reply.Text, tt.wantAlert)
+			assert.True(t, reply.Private)
+		})
+	}
+
+	reply := h.send(t, "alice", "watch", "nope.us", ">", "1")
+	assertReplyError(t, reply, CodeUnknownSymbol)
+	count, _ := store.CountStockAlertsByOwner(context.Background(), "alice")
+	assert.EqualValues(t, 4, count, "Only the valid alerts of known symbols are stored")
+}
+
+func TestWatch_PerUserLimit(t *testing.T) {
+	provider := NewFakeProvider()
+	provider.SetPrice("aapl.us", 150)
+	store := &FakeAlertStore{}
+	h := startAlertBot(t, provider, store, time.Hour)
+
+	for i := range 20 {
+		reply := h.send(t, "alice", "watch", "aapl.us", ">", fmt.Sprint(200+i))
+		assert.Nil(t, reply.Error)
+	}
+
+	reply := h.send(t, "alice", "watch", "aapl.us", ">", "300")
+	assertReplyError(t, reply, CodeInvalidArguments)
+	assert.Contains(t, reply.Error.Message, "You already have 20 alerts")
+
+	reply = h.send(t, "bob", "watch", "aapl.us", ">", "300")
+	assert.Nil(t, reply.Error, "The limit is per user")
+
+	reply = h.send(t, "alice", "unwatch", "#1")
+	assert.Equal(t, "Alert #1 removed", reply.Text)
+	reply = h.send(t, "alice", "watch", "aapl.us", ">", "300")
+	assert.Nil(t, reply.Error)
+}
+
+func TestAlerts_FireOnCrossingAndRearm(t *testing.T) {
+	provider := NewFakeProvider()
+	provider.SetPrice("aapl.us", 150)
+	store := &FakeAlertStore{}
+	h := startAlertBot(t, provider, store, 10*time.Millisecond)
+
+	reply := h.send(t, "alice", "watch", "aapl.us", ">", "200")
+	assert.Equal(t, "Alert #1 AAPL.US > $200.00 set, AAPL.US is now $150.00", reply.Text)
+	assert.True(t, store.get(1).Armed)
+
+	provider.SetPrice("aapl.us", 210)
+	assert.Eventually(t, func() bool { return len(h.notifications()) == 1 }, time.Second, 5*time.Millisecond)
+	notification := h.notifications()[0]
+	assert.Equal(t, "alice", notification.Requester)
+	assert.Equal(t, "general", notification.Room)
+	assert.True(t, notification.Private)
+	assert.Contains(t, notification.Text, "Alert #1 AAPL.US > $200.00 crossed")
+
+	// staying above the threshold does not fire again
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, h.notifications(), 1)
+	assert.Contains(t, h.send(t, "alice", "watchlist").Text, "#1 AAPL.US > $200.00 (triggered)")
+
+	// going back rearms the alert for the next crossing
+	provider.SetPrice("aapl.us", 190)
+	assert.Eventually(t, func() bool { return store.get(1).Armed }, time.Second, 5*time.Millisecond)
+	provider.SetPrice("aapl.us", 205)
+	assert.Eventually(t, func() bool { return len(h.notifications()) == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestAlerts_HeldAtCreationIsNotACrossing(t *testing.T) {
+	provider := NewFakeProvider()
+	provider.SetPrice("aapl.us", 236)
+	store := &FakeAlertStore{}
+	h := startAlertBot(t, provider, store, 10*time.Millisecond)
+
+	reply := h.send(t, "alice", "watch", "aapl.us", ">", "200")
+	assert.Contains(t, reply.Text, "it fires once the price went back and crosses again")
+	assert.False(t, store.get(1).Armed)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, h.notifications(), "The price was already above the threshold")
+	assert.Contains(t, h.send(t, "alice", "watchlist").Text, "#1 AAPL.US > $200.00 (waiting for the price to go back)")
+
+	provider.SetPrice("aapl.us", 195)
+	assert.Eventually(t, func() bool { return store.get(1).Armed }, time.Second, 5*time.Millisecond)
+	assert.Empty(t, h.notifications())
+
+	provider.SetPrice("aapl.us", 201)
+	assert.Eventually(t, func() bool { return len(h.notifications()) == 1 }, time.Second, 5*time.Millisecond)
+}
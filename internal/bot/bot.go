@@ -0,0 +1,241 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LuccChagas/my-chat-app/internal/quotes"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+	"github.com/labstack/gommon/log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	RequestTopic  = "mq_stock_code_req"
+	ResponseTopic = "mq_stock_code_res"
+)
+
+// RequestRetryPolicy retries the requests that failed for a temporary reason
+// before they land in the mq_stock_code_req.dlq dead-letter queue.
+var RequestRetryPolicy = bus.RetryPolicy{MaxAttempts: 3, Backoff: 2 * time.Second}
+
+// DefaultWorkers is how many commands a bot handles at once, so a slow
+// upstream call does not hold the commands of other users.
+const DefaultWorkers = 4
+
+// Bot answers the stock commands published on RequestTopic. It runs either
+// as cmd/bot over RabbitMQ or inside the server process over a memory bus.
+type Bot struct {
+	bus      bus.Bus
+	provider QuoteProvider
+	quotes   *cache[quotes.Quote]
+	workers  int
+	metrics  *metrics
+	running  atomic.Bool
+
+	alerts        AlertStore
+	alertInterval time.Duration
+	stopAlerts    context.CancelFunc
+	alertsDone    chan struct{}
+}
+
+// New creates a bot answering with the quotes of provider, cached for
+// cacheTTL.
+func New(b bus.Bus, provider QuoteProvider, cacheTTL time.Duration) *Bot {
+	bot := &Bot{
+		bus:      b,
+		provider: provider,
+		workers:  DefaultWorkers,
+		metrics:  newMetrics(),
+	}
+	bot.quotes = newCache(cacheTTL, bot.fetchQuote)
+	return bot
+}
+
+// SetWorkers sets how many commands are handled at once. It must be called
+// before Start.
+func (b *Bot) SetWorkers(workers int) {
+	b.workers = workers
+}
+
+// CacheStats reports how the quote lookups were answered.
+func (b *Bot) CacheStats() CacheStats {
+	return b.quotes.Stats()
+}
+
+// DeclareTopics declares the queues shared by the server and the bot. Both
+// call it once at startup so nothing is declared on the publish path.
+func DeclareTopics(b bus.Bus) error {
+	if err := b.Declare(RequestTopic, bus.Queue); err != nil {
+		return err
+	}
+	return b.Declare(ResponseTopic, bus.Queue)
+}
+
+func (b *Bot) Start() error {
+	if err := DeclareTopics(b.bus); err != nil {
+		return err
+	}
+	if err := b.bus.SetRetryPolicy(RequestTopic, RequestRetryPolicy); err != nil {
+		return err
+	}
+	if err := b.bus.SetConcurrency(RequestTopic, b.workers); err != nil {
+		return err
+	}
+	if err := b.bus.Subscribe(RequestTopic, b.handleRequest); err != nil {
+		return err
+	}
+
+	if b.alerts != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		b.stopAlerts, b.alertsDone = cancel, make(chan struct{})
+		go func() {
+			defer close(b.alertsDone)
+			b.watchAlerts(ctx)
+		}()
+	}
+
+	b.running.Store(true)
+	log.Printf("Bot started with %d workers. Waiting for messages on topic %q...", b.workers, RequestTopic)
+	return nil
+}
+
+// Ready tells whether the bot is taking commands: it was started and not
+// stopped, and its bus is connected with a consumer running.
+func (b *Bot) Ready() error {
+	if !b.running.Load() {
+		return errors.New("bot is not running")
+	}
+	status := b.bus.Status()
+	if !status.Connected {
+		return bus.ErrDisconnected
+	}
+	if status.Consumers == 0 {
+		return errors.New("no consumer is running")
+	}
+	return nil
+}
+
+// Stop stops checking the alerts, waiting for a check in progress. The
+// commands are drained by shutting the bus down afterwards.
+func (b *Bot) Stop() {
+	b.running.Store(false)
+	if b.stopAlerts != nil {
+		b.stopAlerts()
+		<-b.alertsDone
+	}
+}
+
+func (b *Bot) handleRequest(ctx context.Context, msg bus.Message) error {
+	req := decodeRequest(msg)
+	log.Printf("Command received: %s /%s %s", req.ID, req.Command, strings.Join(req.Args, " "))
+
+	reply := Reply{
+		ID:        req.ID,
+		Room:      req.Room,
+		Requester: req.Requester,
+	}
+
+	cmd, ok := lookup(req.Command)
+	if !ok {
+		b.metrics.command(req.Command, resultFailed)
+		return b.replyError(ctx, msg.ReplyTo, reply,
+			newCommandError(CodeUnknownCommand, fmt.Sprintf("The bot does not know /%s", req.Command), nil))
+	}
+	if err := cmd.Validate(req.Args); err != nil {
+		b.metrics.command(req.Command, resultFailed)
+		return b.replyError(ctx, msg.ReplyTo, reply, newCommandError(CodeInvalidArguments, err.Error(), nil))
+	}
+
+	result, err := cmd.Run(b, ctx, req)
+	if err != nil {
+		var cmdErr *CommandError
+		if !errors.As(err, &cmdErr) || !cmdErr.Temporary() {
+			b.metrics.command(req.Command, resultFailed)
+			return b.replyError(ctx, msg.ReplyTo, reply, err)
+		}
+		// the user only hears about it once the last attempt failed, the
+		// error is still returned so the request is dead-lettered
+		if bus.Attempt(msg) >= RequestRetryPolicy.MaxAttempts {
+			b.metrics.command(req.Command, resultFailed)
+			return errors.Join(err, b.replyError(ctx, msg.ReplyTo, reply, err))
+		}
+		b.metrics.command(req.Command, resultRetried)
+		log.Printf("Retrying command - %s (attempt %d): %v", req.ID, bus.Attempt(msg), err)
+		b.notifyRetry(ctx, msg, reply)
+		return err
+	}
+
+	b.metrics.command(req.Command, resultOK)
+	reply.Private = cmd.Private
+	reply.Text = result.Text
+	reply.Quotes = result.Quotes
+	return b.reply(ctx, msg.ReplyTo, reply)
+}
+
+// notifyRetry tells the requester that the command is tried again, so the
+// server keeps waiting for the answer instead of reporting a timeout.
+func (b *Bot) notifyRetry(ctx context.Context, msg bus.Message, reply Reply) {
+	delay := RequestRetryPolicy.Delay(bus.Attempt(msg))
+	reply.Private = true
+	reply.RetryIn = int(delay.Round(time.Second) / time.Second)
+	reply.Text = fmt.Sprintf("The quote service did not answer, retrying in %s", delay)
+	if err := b.reply(ctx, msg.ReplyTo, reply); err != nil {
+		log.Printf("Error sending the retry notice - %s: %v", reply.ID, err)
+	}
+}
+
+// replyError tells the requester, and only them, why the command failed.
+func (b *Bot) replyError(ctx context.Context, replyTo string, reply Reply, err error) error {
+	log.Printf("Error processing command - %s: %v", reply.ID, err)
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		cmdErr = newCommandError(CodeInternalError, "The bot could not process the command", err)
+	}
+
+	b.metrics.failure(cmdErr.Code)
+	reply.Private = true
+	reply.Error = &ReplyError{Code: cmdErr.Code, Message: cmdErr.Message}
+	return b.reply(ctx, replyTo, reply)
+}
+
+// reply publishes to the topic the request asked for, or ResponseTopic.
+func (b *Bot) reply(ctx context.Context, replyTo string, reply Reply) error {
+	if replyTo == "" {
+		replyTo = ResponseTopic
+	}
+
+	body, err := json.Marshal(reply)
+	if err != nil {
+		return err
+	}
+
+	err = b.bus.Publish(ctx, replyTo, bus.Message{
+		ContentType:   "application/json",
+		CorrelationID: reply.ID,
+		Body:          body,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Response published: %s", body)
+	return nil
+}
+
+// decodeRequest reads a JSON Request; bare bodies come from servers that
+// predate the command registry and carry a stock code.
+func decodeRequest(msg bus.Message) Request {
+	var req Request
+	if err := json.Unmarshal(msg.Body, &req); err != nil || req.Command == "" {
+		req = Request{Command: "stock", Args: []string{strings.TrimSpace(string(msg.Body))}}
+	}
+	if req.ID == "" {
+		req.ID = msg.CorrelationID
+	}
+	return req
+}
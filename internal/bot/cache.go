@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is how long a quote is served from the cache.
+const DefaultCacheTTL = time.Minute
+
+const (
+	// cacheFetchTimeout bounds a fetch, which no longer depends on the
+	// lookups waiting for it.
+	cacheFetchTimeout = 10 * time.Second
+	// cacheStaleFor is how long an expired value may still be served when
+	// refreshing it fails; older entries are evicted.
+	cacheStaleFor = time.Hour
+)
+
+// CacheStats counts how lookups were answered. Stale lookups got an
+// expired value because refreshing it failed.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+	Stale  int64
+}
+
+// cache keeps the values fetched for a key for ttl. Concurrent lookups of a
+// missing key share one fetch, and when a fetch fails the expired value, if
+// any and not older than staleFor, is returned as stale instead of the error.
+type cache[V any] struct {
+	ttl      time.Duration
+	staleFor time.Duration
+	timeout  time.Duration
+	fetch    func(ctx context.Context, key string) (V, error)
+	now      func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]cacheEntry[V]
+	inflight map[string]*cacheCall[V]
+	evicted  time.Time
+	stats    CacheStats
+}
+
+type cacheEntry[V any] struct {
+	value   V
+	fetched time.Time
+}
+
+// cacheCall is a fetch shared by every lookup made while it runs.
+type cacheCall[V any] struct {
+	done  chan struct{}
+	value V
+	stale bool
+	err   error
+}
+
+func newCache[V any](ttl time.Duration, fetch func(ctx context.Context, key string) (V, error)) *cache[V] {
+	return &cache[V]{
+		ttl:      ttl,
+		staleFor: cacheStaleFor,
+		timeout:  cacheFetchTimeout,
+		fetch:    fetch,
+		now:      time.Now,
+		entries:  make(map[string]cacheEntry[V]),
+		inflight: make(map[string]*cacheCall[V]),
+	}
+}
+
+func (c *cache[V]) get(ctx context.Context, key string) (value V, stale bool, err error) {
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && c.now().Sub(entry.fetched) < c.ttl {
+		c.stats.Hits++
+		c.mu.Unlock()
+		return entry.value, false, nil
+	}
+	c.stats.Misses++
+
+	call, ok := c.inflight[key]
+	if !ok {
+		call = &cacheCall[V]{done: make(chan struct{})}
+		c.inflight[key] = call
+		// the fetch is shared, so it must not end with the lookup that
+		// started it
+		go c.run(context.WithoutCancel(ctx), key, call)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.stale, call.err
+	case <-ctx.Done():
+		return value, false, ctx.Err()
+	}
+}
+
+// run fetches key for the lookups waiting on call.
+func (c *cache[V]) run(ctx context.Context, key string, call *cacheCall[V]) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	value, err := c.fetch(ctx, key)
+
+	c.mu.Lock()
+	now := c.now()
+	if err == nil {
+		c.entries[key] = cacheEntry[V]{value: value, fetched: now}
+	} else if entry, ok := c.entries[key]; ok && now.Sub(entry.fetched) < c.ttl+c.staleFor {
+		value, call.stale, err = entry.value, true, nil
+		c.stats.Stale++
+	}
+	call.value, call.err = value, err
+	delete(c.inflight, key)
+	c.evict(now)
+	c.mu.Unlock()
+
+	close(call.done)
+}
+
+// evict drops the entries that are too old to be served even as stale. It
+// scans the entries at most once per staleFor.
+func (c *cache[V]) evict(now time.Time) {
+	if now.Sub(c.evicted) < c.staleFor {
+		return
+	}
+	c.evicted = now
+
+	for key, entry := range c.entries {
+		if now.Sub(entry.fetched) >= c.ttl+c.staleFor {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *cache[V]) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stats
+}
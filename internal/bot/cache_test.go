@@ -0,0 +1,196 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_TTL(t *testing.T) {
+	fetch := &fakeFetch{}
+	c, clock := newTestCache(time.Minute, fetch.fetch)
+	ctx := context.Background()
+
+	fetch.value.Store(1)
+	value, stale, err := c.get(ctx, "aapl.us")
+	assert.NoError(t, err)
+	assert.False(t, stale)
+	assert.Equal(t, 1, value)
+
+	fetch.value.Store(2)
+	clock.Advance(time.Minute - time.Second)
+	value, _, _ = c.get(ctx, "aapl.us")
+	assert.Equal(t, 1, value, "A fresh value is served from the cache")
+	assert.EqualValues(t, 1, fetch.calls.Load())
+
+	value, _, _ = c.get(ctx, "googl.us")
+	assert.Equal(t, 2, value, "Keys are cached separately")
+
+	clock.Advance(time.Second)
+	value, _, _ = c.get(ctx, "aapl.us")
+	assert.Equal(t, 2, value, "An expired value is fetched again")
+	assert.EqualValues(t, 3, fetch.calls.Load())
+
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 3}, c.Stats())
+}
+
+func TestCache_ZeroTTLAlwaysFetches(t *testing.T) {
+	fetch := &fakeFetch{}
+	c, _ := newTestCache(0, fetch.fetch)
+
+	for range 3 {
+		_, _, _ = c.get(context.Background(), "aapl.us")
+	}
+	assert.EqualValues(t, 3, fetch.calls.Load())
+	assert.Equal(t, CacheStats{Misses: 3}, c.Stats())
+}
+
+func TestCache_ConcurrentLookupsShareOneFetch(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	c, _ := newTestCache(time.Minute, func(ctx context.Context, key string) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	})
+
+	const lookups = 10
+	values := make([]int, lookups)
+	var wg sync.WaitGroup
+	for i := range lookups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values[i], _, _ = c.get(context.Background(), "aapl.us")
+		}()
+	}
+	// every lookup missed and waits for the fetch in flight
+	assert.Eventually(t, func() bool { return c.Stats().Misses == lookups }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, calls.Load())
+	for _, value := range values {
+		assert.Equal(t, 42, value)
+	}
+}
+
+func TestCache_WaiterGivesUpWithItsContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	c, _ := newTestCache(time.Minute, func(ctx context.Context, key string) (int, error) {
+		<-release
+		return 42, nil
+	})
+
+	go func() { _, _, _ = c.get(context.Background(), "aapl.us") }()
+	assert.Eventually(t, func() bool { return c.Stats().Misses == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := c.get(ctx, "aapl.us")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCache_StaleWhileError(t *testing.T) {
+	fetch := &fakeFetch{}
+	c, clock := newTestCache(time.Minute, fetch.fetch)
+	ctx := context.Background()
+	errDown := errors.New("upstream is down")
+
+	fetch.err.Store(&errDown)
+	_, _, err := c.get(ctx, "aapl.us")
+	assert.ErrorIs(t, err, errDown, "Without a previous value the error is returned")
+
+	fetch.err.Store(nil)
+	fetch.value.Store(1)
+	_, _, err = c.get(ctx, "aapl.us")
+	assert.NoError(t, err)
+
+	fetch.err.Store(&errDown)
+	clock.Advance(2 * time.Minute)
+	value, stale, err := c.get(ctx, "aapl.us")
+	assert.NoError(t, err)
+	assert.True(t, stale)
+	assert.Equal(t, 1, value, "The expired value is served when the fetch fails")
+
+	// the stale value is not refreshed, the next lookup tries again
+	fetch.err.Store(nil)
+	fetch.value.Store(2)
+	value, stale, err = c.get(ctx, "aapl.us")
+	assert.NoError(t, err)
+	assert.False(t, stale)
+	assert.Equal(t, 2, value)
+
+	assert.Equal(t, CacheStats{Misses: 4, Stale: 1}, c.Stats())
+	assert.EqualValues(t, 4, fetch.calls.Load())
+}
+
+func TestCache_FetchOutlivesTheFirstLookup(t *testing.T) {
+	release := make(chan struct{})
+	c, _ := newTestCache(time.Minute, func(ctx context.Context, key string) (int, error) {
+		select {
+		case <-release:
+			return 42, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, _, err := c.get(ctx, "aapl.us")
+		first <- err
+	}()
+	assert.Eventually(t, func() bool { return c.Stats().Misses == 1 }, time.Second, time.Millisecond)
+
+	second := make(chan int, 1)
+	go func() {
+		value, _, err := c.get(context.Background(), "aapl.us")
+		assert.NoError(t, err)
+		second <- value
+	}()
+	assert.Eventually(t, func() bool { return c.Stats().Misses == 2 }, time.Second, time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled)
+	close(release)
+	assert.Equal(t, 42, <-second, "The other lookups still get the value")
+}
+
+func TestCache_FetchTimeout(t *testing.T) {
+	c, _ := newTestCache(time.Minute, func(ctx context.Context, key string) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	c.timeout = 10 * time.Millisecond
+
+	_, _, err := c.get(context.Background(), "aapl.us")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestCache_EvictsPastTheStaleWindow(t *testing.T) {
+	fetch := &fakeFetch{}
+	c, clock := newTestCache(time.Minute, fetch.fetch)
+	ctx := context.Background()
+	errDown := errors.New("upstream is down")
+
+	fetch.value.Store(1)
+	_, _, _ = c.get(ctx, "aapl.us")
+	_, _, _ = c.get(ctx, "googl.us")
+
+	fetch.err.Store(&errDown)
+	clock.Advance(time.Minute + c.staleFor)
+	_, _, err := c.get(ctx, "aapl.us")
+	assert.ErrorIs(t, err, errDown, "Values past the stale window are not served")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assert.Empty(t, c.entries, "Every expired entry is evicted, not only the one looked up")
+}
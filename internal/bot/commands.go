@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"github.com/LuccChagas/my-chat-app/internal/commands"
+	"github.com/LuccChagas/my-chat-app/internal/quotes"
+)
+
+// Request is the body published on RequestTopic. ID is also sent as the
+// message correlation id and comes back in the Reply.
+type Request struct {
+	ID        string   `json:"id"`
+	Command   string   `json:"command"`
+	Args      []string `json:"args"`
+	Room      string   `json:"room"`
+	Requester string   `json:"requester"`
+}
+
+// Reply is the body published back to the topic named in the request ReplyTo.
+// Private replies go to the requester only, the others to the request room.
+// Failed commands carry Error instead of Text and are always private.
+type Reply struct {
+	ID        string      `json:"id"`
+	Room      string      `json:"room"`
+	Requester string      `json:"requester"`
+	Private   bool        `json:"private"`
+	Text      string      `json:"text,omitempty"`
+	Quotes    []QuoteCard `json:"quotes,omitempty"`
+	Error     *ReplyError `json:"error,omitempty"`
+	// RetryIn is set, in seconds, on the notice sent before a retry.
+	RetryIn int `json:"retry_in,omitempty"`
+}
+
+// Result is what a command answers. Text is always set for plain-text
+// clients; Quotes lets the others render the answer as cards.
+type Result struct {
+	Text   string
+	Quotes []QuoteCard
+}
+
+// QuoteCard is a quote of a stock reply. Cached quotes are the last known
+// ones, sent because the quote service failed. When a symbol of a
+// multi-symbol reply failed, only Symbol and Error are set.
+type QuoteCard struct {
+	quotes.Quote
+	Cached bool   `json:"cached,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Command is a command answered by the bot. The server forwards every
+// command listed here, so adding one does not touch the websocket code.
+// Private commands answer the requester only.
+type Command struct {
+	commands.Spec
+	Private bool
+	Run     func(b *Bot, ctx context.Context, req Request) (Result, error)
+}
+
+var botCommands = []Command{
+	{
+		Spec: commands.Spec{
+			Name:    "stock",
+			Args:    "<stock_code>[,<stock_code>...]",
+			Help:    fmt.Sprintf("Get the latest quote of up to %d stocks, e.g. /stock aapl.us,googl.us", maxSymbols),
+			MinArgs: 1,
+			MaxArgs: -1,
+		},
+		Run: (*Bot).stock,
+	},
+	{
+		Spec: commands.Spec{
+			Name:    "watch",
+			Args:    "<stock_code> <op> <price>",
+			Help:    "Get a private alert when a stock crosses a price, e.g. /watch aapl.us > 200 (op: >, >=, <, <=)",
+			MinArgs: 1,
+			MaxArgs: 3,
+		},
+		Private: true,
+		Run:     (*Bot).watch,
+	},
+	{
+		Spec: commands.Spec{
+			Name: "watchlist",
+			Help: "List your stock alerts",
+		},
+		Private: true,
+		Run:     (*Bot).watchlist,
+	},
+	{
+		Spec: commands.Spec{
+			Name:    "unwatch",
+			Args:    "<id>",
+			Help:    "Remove one of your stock alerts",
+			MinArgs: 1,
+			MaxArgs: 1,
+		},
+		Private: true,
+		Run:     (*Bot).unwatch,
+	},
+}
+
+// Specs lists the commands answered by the bot.
+func Specs() []commands.Spec {
+	specs := make([]commands.Spec, len(botCommands))
+	for i, cmd := range botCommands {
+		specs[i] = cmd.Spec
+	}
+	return specs
+}
+
+func lookup(name string) (Command, bool) {
+	for _, cmd := range botCommands {
+		if cmd.Name == name {
+			return cmd, true
+		}
+	}
+	return Command{}, false
+}
@@ -0,0 +1,51 @@
+package bot
+
+import "fmt"
+
+const (
+	CodeUnknownCommand    = "unknown_command"
+	CodeInvalidArguments  = "invalid_arguments"
+	CodeUnknownSymbol     = "unknown_symbol"
+	CodeUpstreamTimeout   = "upstream_timeout"
+	CodeUpstreamError     = "upstream_error"
+	CodeMalformedResponse = "malformed_response"
+	CodeNotFound          = "not_found"
+	CodeUnavailable       = "unavailable"
+	CodeInternalError     = "internal_error"
+)
+
+var errAlertsUnavailable = newCommandError(CodeUnavailable, "Alerts are not available on this bot", nil)
+
+// ReplyError is the error sent back to the requester instead of a result.
+type ReplyError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// CommandError is returned by commands that fail in a way the user should
+// be told about. Message is shown to the user, Err is only logged.
+type CommandError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// Temporary reports whether trying the command again may succeed.
+func (e *CommandError) Temporary() bool {
+	return e.Code == CodeUpstreamTimeout || e.Code == CodeUpstreamError
+}
+
+func newCommandError(code, message string, err error) *CommandError {
+	return &CommandError{Code: code, Message: message, Err: err}
+}
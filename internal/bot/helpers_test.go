@@ -0,0 +1,333 @@
+package bot
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+	"github.com/LuccChagas/my-chat-app/internal/quotes"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+)
+
+// fakeClock is a time source moved by the test.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// fakeFetch returns the value set by the test, or err when set, and counts
+// its calls.
+type fakeFetch struct {
+	calls atomic.Int32
+	value atomic.Int32
+	err   atomic.Pointer[error]
+}
+
+func (f *fakeFetch) fetch(ctx context.Context, key string) (int, error) {
+	f.calls.Add(1)
+	if err := f.err.Load(); err != nil {
+		return 0, *err
+	}
+	return int(f.value.Load()), nil
+}
+
+func newTestCache(ttl time.Duration, fetch func(ctx context.Context, key string) (int, error)) (*cache[int], *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 10, 22, 0, 0, 0, time.UTC)}
+	c := newCache(ttl, fetch)
+	c.now = clock.Now
+	return c, clock
+}
+
+// FakeProvider answers with the prices set by the test and records how many
+// lookups run at once.
+type FakeProvider struct {
+	Delay time.Duration
+
+	mu        sync.Mutex
+	prices    map[string]float64
+	calls     map[string]int
+	active    int
+	maxActive int
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		prices: make(map[string]float64),
+		calls:  make(map[string]int),
+	}
+}
+
+func (p *FakeProvider) SetPrice(symbol string, price float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prices[symbol] = price
+}
+
+func (p *FakeProvider) Calls(symbol string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls[symbol]
+}
+
+func (p *FakeProvider) MaxActive() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.maxActive
+}
+
+func (p *FakeProvider) Quote(ctx context.Context, symbol string) (quotes.Quote, error) {
+	p.mu.Lock()
+	p.calls[symbol]++
+	p.active++
+	p.maxActive = max(p.maxActive, p.active)
+	price, known := p.prices[symbol]
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.active--
+		p.mu.Unlock()
+	}()
+
+	time.Sleep(p.Delay)
+	if !known {
+		return quotes.Quote{}, fmt.Errorf("%w: %s", quotes.ErrUnknownSymbol, symbol)
+	}
+	return quotes.Quote{
+		Symbol: strings.ToUpper(symbol),
+		Date:   "2025-01-10",
+		Time:   "22:00:05",
+		Open:   price,
+		High:   price,
+		Low:    price,
+		Close:  price,
+		Volume: 1000,
+	}, nil
+}
+
+// botHarness runs a bot over a memory bus and records its replies.
+type botHarness struct {
+	bus *bus.MemoryBus
+	bot *Bot
+
+	mu      sync.Mutex
+	replies []Reply
+	next    int
+}
+
+// startBot starts a bot without a quote cache; configure, when set, runs
+// before Start.
+func startBot(t *testing.T, provider QuoteProvider, configure func(b *Bot)) *botHarness {
+	h := &botHarness{bus: bus.NewMemoryBus()}
+	h.bot = New(h.bus, provider, 0)
+	if configure != nil {
+		configure(h.bot)
+	}
+	require.NoError(t, h.bot.Start())
+	require.NoError(t, h.bus.Subscribe(ResponseTopic, func(ctx context.Context, msg bus.Message) error {
+		var reply Reply
+		if err := json.Unmarshal(msg.Body, &reply); err != nil {
+			return err
+		}
+		h.mu.Lock()
+		h.replies = append(h.replies, reply)
+		h.mu.Unlock()
+		return nil
+	}))
+	t.Cleanup(func() {
+		h.bot.Stop()
+		_ = h.bus.Close()
+	})
+	return h
+}
+
+// send runs a command and returns its final reply.
+func (h *botHarness) send(t *testing.T, requester, command string, args ...string) Reply {
+	h.mu.Lock()
+	h.next++
+	id := fmt.Sprintf("req-%d", h.next)
+	h.mu.Unlock()
+
+	body, err := json.Marshal(Request{
+		ID:        id,
+		Command:   command,
+		Args:      args,
+		Room:      "general",
+		Requester: requester,
+	})
+	require.NoError(t, err)
+	require.NoError(t, h.bus.Publish(context.Background(), RequestTopic, bus.Message{
+		CorrelationID: id,
+		ReplyTo:       ResponseTopic,
+		Body:          body,
+	}))
+
+	var reply Reply
+	require.Eventually(t, func() bool {
+		replies := h.find(func(r Reply) bool { return r.ID == id && r.RetryIn == 0 })
+		if len(replies) == 0 {
+			return false
+		}
+		reply = replies[0]
+		return true
+	}, 2*time.Second, 5*time.Millisecond, "No reply to /%s", command)
+	return reply
+}
+
+// find returns the replies recorded so far that match.
+func (h *botHarness) find(match func(r Reply) bool) []Reply {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var found []Reply
+	for _, reply := range h.replies {
+		if match(reply) {
+			found = append(found, reply)
+		}
+	}
+	return found
+}
+
+// FakeAlertStore keeps the alerts in memory like the stock_alerts table.
+// TriggerStockAlert only matches armed alerts, as the WHERE armed clause of
+// the query does, so an alert cannot fire twice in a row.
+type FakeAlertStore struct {
+	mu     sync.Mutex
+	alerts []db.StockAlert
+	nextID int64
+}
+
+func (s *FakeAlertStore) CreateStockAlert(ctx context.Context, arg db.CreateStockAlertParams) (db.StockAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	alert := db.StockAlert{
+		ID:        s.nextID,
+		Owner:     arg.Owner,
+		Room:      arg.Room,
+		Symbol:    arg.Symbol,
+		Operator:  arg.Operator,
+		Threshold: arg.Threshold,
+		CreatedAt: time.Now(),
+		Armed:     arg.Armed,
+	}
+	s.alerts = append(s.alerts, alert)
+	return alert, nil
+}
+
+func (s *FakeAlertStore) CountStockAlertsByOwner(ctx context.Context, owner string) (int64, error) {
+	alerts, err := s.ListStockAlertsByOwner(ctx, owner)
+	return int64(len(alerts)), err
+}
+
+func (s *FakeAlertStore) ListStockAlertsByOwner(ctx context.Context, owner string) ([]db.StockAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var alerts []db.StockAlert
+	for _, alert := range s.alerts {
+		if alert.Owner == owner {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts, nil
+}
+
+func (s *FakeAlertStore) ListStockAlerts(ctx context.Context) ([]db.StockAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]db.StockAlert(nil), s.alerts...), nil
+}
+
+func (s *FakeAlertStore) DeleteStockAlert(ctx context.Context, arg db.DeleteStockAlertParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, alert := range s.alerts {
+		if alert.ID == arg.ID && alert.Owner == arg.Owner {
+			s.alerts = append(s.alerts[:i], s.alerts[i+1:]...)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *FakeAlertStore) TriggerStockAlert(ctx context.Context, id int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, alert := range s.alerts {
+		if alert.ID == id && alert.Armed {
+			s.alerts[i].Armed = false
+			s.alerts[i].TriggeredAt = sql.NullTime{Time: time.Now(), Valid: true}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *FakeAlertStore) RearmStockAlert(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, alert := range s.alerts {
+		if alert.ID == id {
+			s.alerts[i].Armed = true
+		}
+	}
+	return nil
+}
+
+func (s *FakeAlertStore) get(id int64) db.StockAlert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, alert := range s.alerts {
+		if alert.ID == id {
+			return alert
+		}
+	}
+	return db.StockAlert{}
+}
+
+func startAlertBot(t *testing.T, provider *FakeProvider, store *FakeAlertStore, interval time.Duration) *botHarness {
+	return startBot(t, provider, func(b *Bot) {
+		b.EnableAlerts(store, interval)
+	})
+}
+
+// notifications returns the alert notifications sent so far.
+func (h *botHarness) notifications() []Reply {
+	return h.find(func(r Reply) bool { return strings.Contains(r.Text, "crossed") })
+}
+
+func assertReplyError(t *testing.T, reply Reply, code string) {
+	t.Helper()
+	if assert.NotNil(t, reply.Error, "Expected a %s error, got %q", code, reply.Text) {
+		assert.Equal(t, code, reply.Error.Code)
+		assert.True(t, reply.Private, "Errors only go to the requester")
+	}
+}
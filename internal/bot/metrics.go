@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Command results counted by bot_commands_total.
+const (
+	resultOK      = "ok"
+	resultFailed  = "failed"
+	resultRetried = "retried"
+)
+
+// upstreamBuckets are the bounds, in seconds, of the upstream latency
+// histogram.
+var upstreamBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// metrics counts what the bot did since it started. It is written in the
+// Prometheus text format by WriteMetrics.
+type metrics struct {
+	mu       sync.Mutex
+	commands map[[2]string]int64
+	failures map[string]int64
+
+	upstreamCounts []int64
+	upstreamSum    float64
+	upstreamTotal  int64
+	upstreamErrors int64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		commands:       make(map[[2]string]int64),
+		failures:       make(map[string]int64),
+		upstreamCounts: make([]int64, len(upstreamBuckets)),
+	}
+}
+
+// command counts a handled command; unknown commands are all counted as
+// "unknown" so user input does not become a label.
+func (m *metrics) command(name, result string) {
+	if _, ok := lookup(name); !ok {
+		name = "unknown"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.commands[[2]string{name, result}]++
+}
+
+func (m *metrics) failure(code string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.failures[code]++
+}
+
+// upstream records a call to the quote provider.
+func (m *metrics) upstream(elapsed time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seconds := elapsed.Seconds()
+	for i, bound := range upstreamBuckets {
+		if seconds <= bound {
+			m.upstreamCounts[i]++
+		}
+	}
+	m.upstreamSum += seconds
+	m.upstreamTotal++
+	if err != nil {
+		m.upstreamErrors++
+	}
+}
+
+// WriteMetrics writes the bot counters in the Prometheus text format.
+func (b *Bot) WriteMetrics(w io.Writer) error {
+	m := b.metrics
+	cache := b.CacheStats()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []byte
+	write := func(format string, args ...any) {
+		out = fmt.Appendf(out, format, args...)
+	}
+
+	write("# HELP bot_commands_total Commands handled, by command and result.\n")
+	write("# TYPE bot_commands_total counter\n")
+	for _, key := range slices.SortedFunc(maps.Keys(m.commands), compareKeys) {
+		write("bot_commands_total{command=%q,result=%q} %d\n", key[0], key[1], m.commands[key])
+	}
+
+	write("# HELP bot_command_failures_total Failed commands, by error code.\n")
+	write("# TYPE bot_command_failures_total counter\n")
+	for _, code := range slices.Sorted(maps.Keys(m.failures)) {
+		write("bot_command_failures_total{code=%q} %d\n", code, m.failures[code])
+	}
+
+	write("# HELP bot_upstream_request_duration_seconds Latency of the quote provider calls.\n")
+	write("# TYPE bot_upstream_request_duration_seconds histogram\n")
+	for i, bound := range upstreamBuckets {
+		write("bot_upstream_request_duration_seconds_bucket{le=%q} %d\n",
+			strconv.FormatFloat(bound, 'g', -1, 64), m.upstreamCounts[i])
+	}
+	write("bot_upstream_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.upstreamTotal)
+	write("bot_upstream_request_duration_seconds_sum %g\n", m.upstreamSum)
+	write("bot_upstream_request_duration_seconds_count %d\n", m.upstreamTotal)
+
+	write("# HELP bot_upstream_errors_total Quote provider calls that failed.\n")
+	write("# TYPE bot_upstream_errors_total counter\n")
+	write("bot_upstream_errors_total %d\n", m.upstreamErrors)
+
+	write("# HELP bot_quote_cache_lookups_total Quote lookups, by how the cache answered them.\n")
+	write("# TYPE bot_quote_cache_lookups_total counter\n")
+	write("bot_quote_cache_lookups_total{result=\"hit\"} %d\n", cache.Hits)
+	write("bot_quote_cache_lookups_total{result=\"miss\"} %d\n", cache.Misses)
+	write("bot_quote_cache_lookups_total{result=\"stale\"} %d\n", cache.Stale)
+
+	_, err := w.Write(out)
+	return err
+}
+
+func compareKeys(a, b [2]string) int {
+	return slices.Compare(a[:], b[:])
+}
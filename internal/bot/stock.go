@@ -0,0 +1,173 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LuccChagas/my-chat-app/internal/quotes"
+)
+
+// QuoteProvider looks up the latest quote of a symbol. Failures wrap one of
+// the quotes package errors so they can be told to the user.
+type QuoteProvider interface {
+	Quote(ctx context.Context, symbol string) (quotes.Quote, error)
+}
+
+// normalizeSymbol makes "AAPL.US " and "aapl.us" the same cache key.
+func normalizeSymbol(symbol string) string {
+	return strings.ToLower(strings.TrimSpace(symbol))
+}
+
+// fetchQuote asks the provider and turns its failures into command errors.
+func (b *Bot) fetchQuote(ctx context.Context, symbol string) (quotes.Quote, error) {
+	start := time.Now()
+	quote, err := b.provider.Quote(ctx, symbol)
+	b.metrics.upstream(time.Since(start), err)
+	switch {
+	case err == nil:
+		return quote, nil
+	case errors.Is(err, quotes.ErrUnknownSymbol):
+		return quote, newCommandError(CodeUnknownSymbol, fmt.Sprintf("Unknown stock symbol %s", strings.ToUpper(symbol)), err)
+	case errors.Is(err, quotes.ErrTimeout):
+		return quote, newCommandError(CodeUpstreamTimeout, "The quote service did not answer in time, try again later", err)
+	case errors.Is(err, quotes.ErrMalformed):
+		return quote, newCommandError(CodeMalformedResponse, "The quote service sent an invalid answer", err)
+	default:
+		return quote, newCommandError(CodeUpstreamError, "The quote service is unavailable, try again later", err)
+	}
+}
+
+const (
+	// maxSymbols is how many symbols one /stock command may ask for.
+	maxSymbols = 10
+	// maxParallelQuotes caps the quote lookups made at once by a command.
+	maxParallelQuotes = 4
+)
+
+// stock answers "/stock aapl.us" or "/stock aapl.us,googl.us". With several
+// symbols the failed ones are reported inline, and the command only fails
+// when every symbol did.
+func (b *Bot) stock(ctx context.Context, req Request) (Result, error) {
+	symbols, err := parseSymbols(req.Args)
+	if err != nil {
+		return Result{}, err
+	}
+
+	cards := make([]QuoteCard, len(symbols))
+	errs := make([]error, len(symbols))
+	sem := make(chan struct{}, maxParallelQuotes)
+	var wg sync.WaitGroup
+	for i, symbol := range symbols {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			quote, stale, err := b.quotes.get(ctx, symbol)
+			if err != nil {
+				errs[i] = err
+				cards[i] = QuoteCard{Quote: quotes.Quote{Symbol: strings.ToUpper(symbol)}, Error: userMessage(err)}
+				return
+			}
+			cards[i] = QuoteCard{Quote: quote, Cached: stale}
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed == len(symbols) {
+		return Result{}, errs[0]
+	}
+
+	if len(cards) == 1 {
+		return Result{Text: formatQuote(cards[0]), Quotes: cards}, nil
+	}
+	lines := make([]string, len(cards))
+	for i, card := range cards {
+		lines[i] = formatQuoteLine(card)
+	}
+	return Result{Text: "Quotes:\n" + strings.Join(lines, "\n"), Quotes: cards}, nil
+}
+
+// parseSymbols splits the comma separated symbols, dropping duplicates.
+func parseSymbols(args []string) ([]string, error) {
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		for _, symbol := range strings.Split(arg, ",") {
+			symbol = normalizeSymbol(symbol)
+			if symbol == "" || seen[symbol] {
+				continue
+			}
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	if len(symbols) == 0 {
+		return nil, newCommandError(CodeInvalidArguments, "usage: /stock <stock_code>[,<stock_code>...]", nil)
+	}
+	if len(symbols) > maxSymbols {
+		return nil, newCommandError(CodeInvalidArguments, fmt.Sprintf("at most %d stocks can be asked at once", maxSymbols), nil)
+	}
+	return symbols, nil
+}
+
+// userMessage returns the part of err that can be shown to users.
+func userMessage(err error) string {
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Message
+	}
+	return "The quote could not be fetched"
+}
+
+// formatQuote renders a card for plain-text clients, e.g.
+// "AAPL.US quote is $236.85 per share (range $233.00 - $240.16, volume
+// 61,710,856, -1.32% vs open)".
+func formatQuote(card QuoteCard) string {
+	text := fmt.Sprintf("%s quote is $%.2f per share (range $%.2f - $%.2f, volume %s, %+.2f%% vs open)",
+		card.Symbol, card.Close, card.Low, card.High, formatVolume(card.Volume), card.ChangePercent())
+	if card.Cached {
+		text += " (cached)"
+	}
+	return text
+}
+
+// formatQuoteLine renders one row of a multi-symbol reply.
+func formatQuoteLine(card QuoteCard) string {
+	if card.Error != "" {
+		return fmt.Sprintf("%s: %s", card.Symbol, card.Error)
+	}
+
+	line := fmt.Sprintf("%s: $%.2f, %+.2f%% vs open, range $%.2f - $%.2f, volume %s",
+		card.Symbol, card.Close, card.ChangePercent(), card.Low, card.High, formatVolume(card.Volume))
+	if card.Cached {
+		line += " (cached)"
+	}
+	return line
+}
+
+// formatVolume groups the digits by thousands.
+func formatVolume(volume int64) string {
+	digits := strconv.FormatInt(volume, 10)
+	var sb strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(digit)
+	}
+	return sb.String()
+}
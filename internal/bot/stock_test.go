@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStock_SingleSymbol(t *testing.T) {
+	provider := NewFakeProvider()
+	provider.SetPrice("aapl.us", 236.85)
+	h := startBot(t, provider, nil)
+
+	reply := h.send(t, "alice", "stock", "AAPL.US")
+	assert.Nil(t, reply.Error)
+	assert.False(t, reply.Private)
+	assert.Equal(t, "AAPL.US quote is $236.85 per share (range $236.85 - $236.85, volume 1,000, +0.00% vs open)", reply.Text)
+	if assert.Len(t, reply.Quotes, 1) {
+		assert.Equal(t, "AAPL.US", reply.Quotes[0].Symbol)
+	}
+}
+
+func TestStock_DeduplicatesSymbols(t *testing.T) {
+	provider := NewFakeProvider()
+	provider.SetPrice("aapl.us", 236.85)
+	provider.SetPrice("googl.us", 192.04)
+	h := startBot(t, provider, nil)
+
+	reply := h.send(t, "alice", "stock", "aapl.us,AAPL.US,", " googl.us", "aapl.us")
+	assert.Nil(t, reply.Error)
+	if assert.Len(t, reply.Quotes, 2) {
+		assert.Equal(t, "AAPL.US", reply.Quotes[0].Symbol)
+		assert.Equal(t, "GOOGL.US", reply.Quotes[1].Symbol)
+	}
+	assert.Equal(t, 1, provider.Calls("aapl.us"))
+	assert.Equal(t, 1, provider.Calls("googl.us"))
+}
+
+func TestStock_SymbolLimit(t *testing.T) {
+	provider := NewFakeProvider()
+	symbols := make([]string, 11)
+	for i := range symbols {
+		symbols[i] = fmt.Sprintf("s%d.us", i)
+		provider.SetPrice(symbols[i], float64(i+1))
+	}
+	h := startBot(t, provider, nil)
+
+	reply := h.send(t, "alice", "stock", symbols...)
+	assertReplyError(t, reply, CodeInvalidArguments)
+	assert.Equal(t, "at most 10 stocks can be asked at once", reply.Error.Message)
+
+	// duplicates do not count
+	reply = h.send(t, "alice", "stock", append(symbols[:10:10], symbols[0])...)
+	assert.Nil(t, reply.Error)
+	assert.Len(t, reply.Quotes, 10)
+
+	reply = h.send(t, "alice", "stock", ",", " ")
+	assertReplyError(t, reply, CodeInvalidArguments)
+}
+
+func TestStock_ConcurrencyCap(t *testing.T) {
+	provider := NewFakeProvider()
+	provider.Delay = 20 * time.Millisecond
+	symbols := make([]string, 10)
+	for i := range symbols {
+		symbols[i] = fmt.Sprintf("s%d.us", i)
+		provider.SetPrice(symbols[i], float64(i+1))
+	}
+	h := startBot(t, provider, nil)
+
+	reply := h.send(t, "alice", "stock", symbols...)
+	assert.Nil(t, reply.Error)
+	assert.Len(t, reply.Quotes, 10)
+	assert.Equal(t, 4, provider.MaxActive(), "A command looks up at most 4 quotes at once")
+	for i, card := range reply.Quotes {
+		assert.Equal(t, float64(i+1), card.Close, "The quotes keep the order of the symbols")
+	}
+}
+
+func TestStock_InlineErrors(t *testing.T) {
+	provider := NewFakeProvider()
+	provider.SetPrice("aapl.us", 236.85)
+	h := startBot(t, provider, nil)
+
+	reply := h.send(t, "alice", "stock", "aapl.us,nope.us")
+	assert.Nil(t, reply.Error, "The command succeeds when some symbols do")
+	assert.Equal(t, "Quotes:\n"+
+		"AAPL.US: $236.85, +0.00% vs open, range $236.85 - $236.85, volume 1,000\n"+
+		"NOPE.US: Unknown stock symbol NOPE.US", reply.Text)
+	if assert.Len(t, reply.Quotes, 2) {
+		assert.Empty(t, reply.Quotes[0].Error)
+		assert.Equal(t, "NOPE.US", reply.Quotes[1].Symbol)
+		assert.Equal(t, "Unknown stock symbol NOPE.US", reply.Quotes[1].Error)
+	}
+}
+
+func TestStock_EverySymbolFails(t *testing.T) {
+	provider := NewFakeProvider()
+	h := startBot(t, provider, nil)
+
+	reply := h.send(t, "alice", "stock", "nope.us,gone.us")
+	assertReplyError(t, reply, CodeUnknownSymbol)
+	assert.Equal(t, "Unknown stock symbol NOPE.US", reply.Error.Message, "The first failure is reported")
+	assert.Empty(t, reply.Quotes)
+}
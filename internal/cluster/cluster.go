@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/LuccChagas/my-chat-app/internal/websocket"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+	"github.com/labstack/gommon/log"
+	"time"
+)
+
+const (
+	Topic        = "chat_broadcast"
+	nodeHeader   = "node_id"
+	outboundSize = 256
+)
+
+var ErrOutboundFull = errors.New("cluster outbound buffer is full")
+
+// Relay shares hub broadcasts between server instances through a fanout
+// topic. Every node tags what it publishes with its node id and drops its
+// own echoes.
+type Relay struct {
+	bus      bus.Bus
+	hub      *websocket.Hub
+	nodeID   string
+	outbound chan websocket.Envelope
+}
+
+func NewRelay(b bus.Bus, hub *websocket.Hub, nodeID string) *Relay {
+	return &Relay{
+		bus:      b,
+		hub:      hub,
+		nodeID:   nodeID,
+		outbound: make(chan websocket.Envelope, outboundSize),
+	}
+}
+
+// Start declares the fanout topic and starts publishing and consuming.
+func (r *Relay) Start() error {
+	if err := r.bus.Declare(Topic, bus.Fanout); err != nil {
+		return err
+	}
+	if err := r.bus.Subscribe(Topic, r.handle); err != nil {
+		return err
+	}
+
+	go r.publishLoop()
+
+	log.Printf("Cluster mode enabled, node %s", r.nodeID)
+	return nil
+}
+
+// Publish queues env for the other nodes without blocking the hub.
+func (r *Relay) Publish(env websocket.Envelope) error {
+	select {
+	case r.outbound <- env:
+		return nil
+	default:
+		return ErrOutboundFull
+	}
+}
+
+func (r *Relay) publishLoop() {
+	for env := range r.outbound {
+		body, err := json.Marshal(env)
+		if err != nil {
+			log.Printf("Error encoding cluster broadcast: %v", err)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = r.bus.Publish(ctx, Topic, bus.Message{
+			ContentType: "application/json",
+			Headers:     map[string]string{nodeHeader: r.nodeID},
+			Body:        body,
+		})
+		cancel()
+		if err != nil {
+			log.Printf("Error publishing cluster broadcast: %v", err)
+		}
+	}
+}
+
+func (r *Relay) handle(ctx context.Context, msg bus.Message) error {
+	if msg.Headers[nodeHeader] == r.nodeID {
+		return nil
+	}
+
+	var env websocket.Envelope
+	if err := json.Unmarshal(msg.Body, &env); err != nil {
+		return err
+	}
+	r.hub.Remote <- env
+	return nil
+}
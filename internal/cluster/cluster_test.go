@@ -0,0 +1,45 @@
+package cluster_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ws "github.com/LuccChagas/my-chat-app/internal/websocket"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+)
+
+func TestRelay_DeliversOtherNodesBroadcastsOnce(t *testing.T) {
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+
+	hubA := startNode(t, memoryBus, "node-a")
+	hubB := startNode(t, memoryBus, "node-b")
+	alice := connect(hubA, "alice")
+	bob := connect(hubB, "bob")
+
+	hubA.Broadcast <- ws.SystemEnvelope(ws.DefaultRoom, "from a")
+	hubB.Broadcast <- ws.SystemEnvelope(ws.DefaultRoom, "from b")
+
+	// each node delivers its own broadcast locally and drops the echo
+	assert.ElementsMatch(t, []string{"from a", "from b"}, systemTexts(t, alice))
+	assert.ElementsMatch(t, []string{"from a", "from b"}, systemTexts(t, bob))
+}
+
+func TestRelay_DoesNotRelayRemoteBroadcastsAgain(t *testing.T) {
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+
+	hubA := startNode(t, memoryBus, "node-a")
+	hubB := startNode(t, memoryBus, "node-b")
+	hubC := startNode(t, memoryBus, "node-c")
+	alice := connect(hubA, "alice")
+	bob := connect(hubB, "bob")
+	carol := connect(hubC, "carol")
+
+	hubA.Broadcast <- ws.SystemEnvelope(ws.DefaultRoom, "hello")
+
+	assert.Equal(t, []string{"hello"}, systemTexts(t, alice))
+	assert.Equal(t, []string{"hello"}, systemTexts(t, bob))
+	assert.Equal(t, []string{"hello"}, systemTexts(t, carol))
+}
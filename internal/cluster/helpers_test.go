@@ -0,0 +1,55 @@
+package cluster_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/LuccChagas/my-chat-app/internal/cluster"
+	ws "github.com/LuccChagas/my-chat-app/internal/websocket"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+)
+
+// startNode runs a hub relayed over b, as StartCluster does.
+func startNode(t *testing.T, b bus.Bus, nodeID string) *ws.Hub {
+	hub := ws.NewHub()
+	hub.IdleAfter = 0
+	relay := cluster.NewRelay(b, hub, nodeID)
+	require.NoError(t, relay.Start())
+	hub.Relay = relay
+	hub.NodeID = nodeID
+	go hub.Run()
+	return hub
+}
+
+func connect(hub *ws.Hub, nickname string) *ws.Client {
+	client := &ws.Client{
+		Hub:      hub,
+		Send:     make(chan []byte, 64),
+		Nickname: nickname,
+		Room:     ws.DefaultRoom,
+		Format:   ws.FormatJSON,
+	}
+	hub.Register <- client
+	return client
+}
+
+// systemTexts returns the system messages received by client until it was
+// quiet for a while.
+func systemTexts(t *testing.T, client *ws.Client) []string {
+	var texts []string
+	for {
+		select {
+		case frame := <-client.Send:
+			var env ws.Envelope
+			require.NoError(t, json.Unmarshal(frame, &env))
+			if env.Type == ws.TypeSystem {
+				texts = append(texts, env.Text())
+			}
+		case <-time.After(100 * time.Millisecond):
+			return texts
+		}
+	}
+}
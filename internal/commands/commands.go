@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/LuccChagas/my-chat-app/internal/websocket"
+)
+
+var ErrNotACommand = errors.New("not a command")
+
+// Spec describes a slash command: its name, argument syntax and help text.
+// MaxArgs < 0 accepts any number of arguments.
+type Spec struct {
+	Name    string
+	Args    string
+	Help    string
+	MinArgs int
+	MaxArgs int
+}
+
+func (s Spec) Usage() string {
+	if s.Args == "" {
+		return "/" + s.Name
+	}
+	return "/" + s.Name + " " + s.Args
+}
+
+// Validate checks the number of arguments against the spec.
+func (s Spec) Validate(args []string) error {
+	if len(args) < s.MinArgs || (s.MaxArgs >= 0 && len(args) > s.MaxArgs) {
+		return fmt.Errorf("usage: %s", s.Usage())
+	}
+	return nil
+}
+
+// Invocation is one run of a command; ID identifies it in acks and replies.
+type Invocation struct {
+	ID     string
+	Name   string
+	Args   []string
+	Client *websocket.Client
+}
+
+type Handler func(ctx context.Context, inv Invocation) error
+
+type Command struct {
+	Spec
+	Handler Handler
+}
+
+type Registry struct {
+	commands map[string]Command
+}
+
+// NewRegistry returns a registry that already knows the /help command.
+func NewRegistry() *Registry {
+	r := &Registry{commands: make(map[string]Command)}
+	r.Register(Command{
+		Spec: Spec{
+			Name: "help",
+			Help: "List the available commands",
+		},
+		Handler: r.help,
+	})
+	return r
+}
+
+func (r *Registry) Register(cmd Command) {
+	r.commands[strings.ToLower(cmd.Name)] = cmd
+}
+
+// Specs returns the registered commands sorted by name.
+func (r *Registry) Specs() []Spec {
+	specs := make([]Spec, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		specs = append(specs, cmd.Spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// Dispatch parses line and runs the matching command under the given id.
+func (r *Registry) Dispatch(ctx context.Context, id string, client *websocket.Client, line string) error {
+	name, args, err := Parse(line)
+	if err != nil {
+		return err
+	}
+
+	cmd, ok := r.commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command /%s, type /help to list the commands", name)
+	}
+	if err = cmd.Validate(args); err != nil {
+		return err
+	}
+
+	return cmd.Handler(ctx, Invocation{ID: id, Name: name, Args: args, Client: client})
+}
+
+func (r *Registry) help(ctx context.Context, inv Invocation) error {
+	lines := []string{"Available commands:"}
+	for _, spec := range r.Specs() {
+		lines = append(lines, fmt.Sprintf("%s - %s", spec.Usage(), spec.Help))
+	}
+
+	inv.Client.SendEnvelope(websocket.SystemEnvelope(inv.Client.Room, strings.Join(lines, "\n")))
+	return nil
+}
+
+// Parse splits "/cmd arg1 arg2" into its name and arguments. The legacy
+// "/cmd=arg" form is accepted as well.
+func Parse(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "/") || len(line) < 2 {
+		return "", nil, ErrNotACommand
+	}
+
+	fields := strings.Fields(line[1:])
+	name, legacyArg, _ := strings.Cut(fields[0], "=")
+	args := fields[1:]
+	if legacyArg != "" {
+		args = append([]string{legacyArg}, args...)
+	}
+
+	return strings.ToLower(name), args, nil
+}
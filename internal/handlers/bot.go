@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"bytes"
+	"github.com/LuccChagas/my-chat-app/internal/models"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+	"github.com/labstack/echo/v4"
+	"io"
+	"net/http"
+)
+
+// metricsContentType is the Prometheus text exposition format.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// BotStatus is implemented by bot.Bot.
+type BotStatus interface {
+	Ready() error
+	WriteMetrics(w io.Writer) error
+}
+
+// BotHandler serves the probes and the metrics of cmd/bot. These endpoints
+// are not part of the chat API.
+type BotHandler struct {
+	bot BotStatus
+	bus bus.Bus
+}
+
+func NewBotHandler(b BotStatus, messageBus bus.Bus) *BotHandler {
+	return &BotHandler{
+		bot: b,
+		bus: messageBus,
+	}
+}
+
+// GetLiveHandler answers as long as the process serves requests.
+func (h *BotHandler) GetLiveHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, models.HealthStatus{
+		Status: "ok",
+		Bus:    h.bus.Status(),
+	})
+}
+
+// GetReadyHandler answers 503 until the bot consumes commands from a
+// connected broker, and again once it is shutting down.
+func (h *BotHandler) GetReadyHandler(c echo.Context) error {
+	response := models.HealthStatus{
+		Status: "ok",
+		Bus:    h.bus.Status(),
+	}
+	if err := h.bot.Ready(); err != nil {
+		response.Status = "unavailable"
+		response.Error = err.Error()
+		return c.JSON(http.StatusServiceUnavailable, response)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// GetMetricsHandler writes the bot counters in the Prometheus text format.
+func (h *BotHandler) GetMetricsHandler(c echo.Context) error {
+	var body bytes.Buffer
+	if err := h.bot.WriteMetrics(&body); err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.Blob(http.StatusOK, metricsContentType, body.Bytes())
+}
@@ -0,0 +1,78 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LuccChagas/my-chat-app/internal/handlers"
+	"github.com/LuccChagas/my-chat-app/internal/models"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+)
+
+func TestBotHandler_Probes(t *testing.T) {
+	messageBus := &statusBus{MemoryBus: bus.NewMemoryBus()}
+	h := handlers.NewBotHandler(startBot(t, messageBus), messageBus)
+
+	tests := []struct {
+		name      string
+		status    bus.Status
+		wantReady int
+	}{
+		{name: "disconnected", status: bus.Status{Connected: false, Reconnects: 2, LastError: "connection refused"}, wantReady: http.StatusServiceUnavailable},
+		{name: "no consumer", status: bus.Status{Connected: true}, wantReady: http.StatusServiceUnavailable},
+		{name: "connected", status: bus.Status{Connected: true, Consumers: 1}, wantReady: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messageBus.setStatus(tt.status)
+
+			rec := serve(h.GetReadyHandler, "/readyz")
+			assert.Equal(t, tt.wantReady, rec.Code)
+			var ready models.HealthStatus
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &ready))
+			assert.Equal(t, tt.status, ready.Bus)
+			if tt.wantReady == http.StatusOK {
+				assert.Equal(t, "ok", ready.Status)
+			} else {
+				assert.Equal(t, "unavailable", ready.Status)
+				assert.NotEmpty(t, ready.Error)
+			}
+
+			rec = serve(h.GetLiveHandler, "/healthz")
+			assert.Equal(t, http.StatusOK, rec.Code, "The process is alive even when the broker is not")
+		})
+	}
+}
+
+func TestBotHandler_Metrics(t *testing.T) {
+	messageBus := bus.NewMemoryBus()
+	b := startBot(t, messageBus)
+	h := handlers.NewBotHandler(b, messageBus)
+
+	reply := runCommand(t, messageBus, "stock", "aapl.us")
+	require.Nil(t, reply.Error)
+	reply = runCommand(t, messageBus, "stock", "nope.us")
+	require.NotNil(t, reply.Error)
+
+	rec := serve(h.GetMetricsHandler, "/metrics")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# HELP bot_commands_total Commands handled, by command and result.\n",
+		"# TYPE bot_commands_total counter\n",
+		"bot_commands_total{command=\"stock\",result=\"ok\"} 1\n",
+		"bot_commands_total{command=\"stock\",result=\"failed\"} 1\n",
+		"bot_command_failures_total{code=\"unknown_symbol\"} 1\n",
+		"# TYPE bot_upstream_request_duration_seconds histogram\n",
+		"bot_upstream_request_duration_seconds_bucket{le=\"+Inf\"} 2\n",
+		"bot_upstream_request_duration_seconds_count 2\n",
+	} {
+		assert.Contains(t, body, line)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/LuccChagas/my-chat-app/internal/models"
+	"github.com/LuccChagas/my-chat-app/internal/services"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+)
+
+type DirectMessageHandler struct {
+	service *services.DirectMessageService
+}
+
+func NewDirectMessageHandler(s *services.DirectMessageService) *DirectMessageHandler {
+	return &DirectMessageHandler{
+		service: s,
+	}
+}
+
+// GetDirectChannelsHandler godoc
+// @Summary List direct message channels
+// @Description List the one-to-one conversations of the logged in user, most recent first, with their last message.
+// @Tags DirectMessage
+// @Produce json
+// @Success 200 {array} models.DirectChannel
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /dm [get]
+func (h *DirectMessageHandler) GetDirectChannelsHandler(c echo.Context) error {
+	nickname, err := sessionNickname(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, err.Error())
+	}
+
+	channels, err := h.service.ListChannels(c.Request().Context(), nickname)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, channels)
+}
+
+// GetDirectMessagesHandler godoc
+// @Summary Get direct messages
+// @Description Retrieve one page of the conversation between the logged in user and another user, oldest message first. Pass next_cursor as "before" to load older messages.
+// @Tags DirectMessage
+// @Produce json
+// @Param nickname path string true "The other participant"
+// @Param before query string false "Cursor returned as next_cursor by the previous page"
+// @Param limit query int false "Page size (default 50, max 100)"
+// @Success 200 {object} models.DirectMessagePage
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /dm/{nickname}/messages [get]
+func (h *DirectMessageHandler) GetDirectMessagesHandler(c echo.Context) error {
+	nickname, err := sessionNickname(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, err.Error())
+	}
+
+	limit := 0
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, "Invalid limit")
+		}
+		limit = parsed
+	}
+
+	var response models.DirectMessagePage
+	response, err = h.service.GetDirectMessages(c.Request().Context(), nickname, c.Param("nickname"), c.QueryParam("before"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) || errors.Is(err, services.ErrEmptyNickname) {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
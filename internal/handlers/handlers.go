@@ -9,6 +9,34 @@ type UserHandlerInterface interface {
 	UserLoginHandler(c echo.Context) error
 }
 
+type MessageHandlerInterface interface {
+	GetRoomMessagesHandler(c echo.Context) error
+	EditMessageHandler(c echo.Context) error
+	DeleteMessageHandler(c echo.Context) error
+	GetMessageHistoryHandler(c echo.Context) error
+	AddReactionHandler(c echo.Context) error
+	RemoveReactionHandler(c echo.Context) error
+}
+
+type DirectMessageHandlerInterface interface {
+	GetDirectChannelsHandler(c echo.Context) error
+	GetDirectMessagesHandler(c echo.Context) error
+}
+
+type PresenceHandlerInterface interface {
+	GetPresenceHandler(c echo.Context) error
+}
+
+type HealthHandlerInterface interface {
+	GetHealthHandler(c echo.Context) error
+}
+
+type BotHandlerInterface interface {
+	GetLiveHandler(c echo.Context) error
+	GetReadyHandler(c echo.Context) error
+	GetMetricsHandler(c echo.Context) error
+}
+
 type WsHandlerInterface interface {
 	WsHandler(echo.Context) error
 }
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"github.com/LuccChagas/my-chat-app/internal/models"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type HealthHandler struct {
+	bus bus.Bus
+}
+
+func NewHealthHandler(b bus.Bus) *HealthHandler {
+	return &HealthHandler{
+		bus: b,
+	}
+}
+
+// GetHealthHandler godoc
+// @Summary Health check
+// @Description Report whether the server is connected to its message bus.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} models.HealthStatus
+// @Failure 503 {object} models.HealthStatus
+// @Router /healthz [get]
+func (h *HealthHandler) GetHealthHandler(c echo.Context) error {
+	response := models.HealthStatus{
+		Status: "ok",
+		Bus:    h.bus.Status(),
+	}
+	if !response.Bus.Connected {
+		response.Status = "unavailable"
+		return c.JSON(http.StatusServiceUnavailable, response)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
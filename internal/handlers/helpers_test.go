@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LuccChagas/my-chat-app/internal/bot"
+	"github.com/LuccChagas/my-chat-app/internal/quotes"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+)
+
+const fixturesDir = "../../fixtures/quotes"
+
+// statusBus is a memory bus reporting the status set by the test.
+type statusBus struct {
+	*bus.MemoryBus
+
+	mu     sync.Mutex
+	status bus.Status
+}
+
+func (b *statusBus) Status() bus.Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.status
+}
+
+func (b *statusBus) setStatus(status bus.Status) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.status = status
+}
+
+// startBot runs a bot answering from the quote fixtures.
+func startBot(t *testing.T, messageBus bus.Bus) *bot.Bot {
+	b := bot.New(messageBus, quotes.NewFiles(fixturesDir), 0)
+	require.NoError(t, b.Start())
+	t.Cleanup(func() {
+		b.Stop()
+		_ = messageBus.Close()
+	})
+	return b
+}
+
+var requests atomic.Int32
+
+// runCommand sends a command to the bot and waits for its reply.
+func runCommand(t *testing.T, messageBus bus.Bus, command string, args ...string) bot.Reply {
+	id := fmt.Sprintf("req-%d", requests.Add(1))
+	replyTo := "replies." + id
+	replies := make(chan bot.Reply, 1)
+	require.NoError(t, messageBus.Subscribe(replyTo, func(ctx context.Context, msg bus.Message) error {
+		var reply bot.Reply
+		if err := json.Unmarshal(msg.Body, &reply); err != nil {
+			return err
+		}
+		replies <- reply
+		return nil
+	}))
+
+	body, err := json.Marshal(bot.Request{ID: id, Command: command, Args: args, Room: "general", Requester: "alice"})
+	require.NoError(t, err)
+	require.NoError(t, messageBus.Publish(context.Background(), bot.RequestTopic, bus.Message{
+		CorrelationID: id,
+		ReplyTo:       replyTo,
+		Body:          body,
+	}))
+
+	select {
+	case reply := <-replies:
+		return reply
+	case <-time.After(2 * time.Second):
+		t.Fatalf("No reply to /%s", command)
+		return bot.Reply{}
+	}
+}
+
+// serve runs handler on a GET request to path.
+func serve(handler echo.HandlerFunc, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, path, nil), rec)
+	if err := handler(c); err != nil {
+		rec.Code = http.StatusInternalServerError
+	}
+	return rec
+}
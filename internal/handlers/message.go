@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/LuccChagas/my-chat-app/internal/models"
+	"github.com/LuccChagas/my-chat-app/internal/services"
+	socket "github.com/LuccChagas/my-chat-app/internal/websocket"
+	"github.com/LuccChagas/my-chat-app/utils"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type MessageHandler struct {
+	service *services.MessageService
+	hub     *socket.Hub
+}
+
+func NewMessageHandler(s *services.MessageService, hub *socket.Hub) *MessageHandler {
+	return &MessageHandler{
+		service: s,
+		hub:     hub,
+	}
+}
+
+// GetRoomMessagesHandler godoc
+// @Summary Get room messages
+// @Description Retrieve one page of a room history, oldest message first, with the reaction counts of each message. Pass next_cursor as "before" to load older messages.
+// @Tags Message
+// @Produce json
+// @Param room path string true "Room name"
+// @Param before query string false "Cursor returned as next_cursor by the previous page"
+// @Param limit query int false "Page size (default 50, max 100)"
+// @Success 200 {object} models.MessagePage
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /rooms/{room}/messages [get]
+func (h *MessageHandler) GetRoomMessagesHandler(c echo.Context) error {
+	nickname, err := sessionNickname(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, err.Error())
+	}
+	room := strings.ToLower(c.Param("room"))
+	if !socket.ValidRoom(room) {
+		return c.JSON(http.StatusBadRequest, "Invalid room name")
+	}
+
+	limit := 0
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, "Invalid limit")
+		}
+		limit = parsed
+	}
+
+	var response models.MessagePage
+	response, err = h.service.GetMessages(c.Request().Context(), room, nickname, c.QueryParam("before"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// EditMessageHandler godoc
+// @Summary Edit a message
+// @Description Replace the content of a room message. Only its author or a moderator may edit it; the previous content is kept in its history and connected clients get an update envelope.
+// @Tags Message
+// @Accept json
+// @Produce json
+// @Param id path string true "Message ID"
+// @Param message body models.EditMessageRequest true "New content"
+// @Success 200 {object} models.Message
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /messages/{id} [put]
+func (h *MessageHandler) EditMessageHandler(c echo.Context) error {
+	nickname, err := sessionNickname(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, err.Error())
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid message id")
+	}
+
+	var request models.EditMessageRequest
+	if err := c.Bind(&request); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if err := utils.Validate(request); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	message, err := h.service.EditMessage(c.Request().Context(), id, nickname, request.Content)
+	if err != nil {
+		return c.JSON(changeErrorStatus(err), err.Error())
+	}
+
+	h.hub.Broadcast <- services.UpdateEnvelope(message)
+	return c.JSON(http.StatusOK, message)
+}
+
+// DeleteMessageHandler godoc
+// @Summary Delete a message
+// @Description Turn a room message into a tombstone. Only its author or a moderator may delete it; connected clients get an update envelope.
+// @Tags Message
+// @Produce json
+// @Param id path string true "Message ID"
+// @Success 200 {object} models.Message
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /messages/{id} [delete]
+func (h *MessageHandler) DeleteMessageHandler(c echo.Context) error {
+	nickname, err := sessionNickname(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, err.Error())
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid message id")
+	}
+
+	message, err := h.service.DeleteMessage(c.Request().Context(), id, nickname)
+	if err != nil {
+		return c.JSON(changeErrorStatus(err), err.Error())
+	}
+
+	h.hub.Broadcast <- services.UpdateEnvelope(message)
+	return c.JSON(http.StatusOK, message)
+}
+
+// GetMessageHistoryHandler godoc
+// @Summary Get message history
+// @Description List the previous versions of a message, oldest first, including the content it had before being deleted. Only its author or a moderator may see them.
+// @Tags Message
+// @Produce json
+// @Param id path string true "Message ID"
+// @Success 200 {array} models.MessageEdit
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /messages/{id}/history [get]
+func (h *MessageHandler) GetMessageHistoryHandler(c echo.Context) error {
+	nickname, err := sessionNickname(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, err.Error())
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid message id")
+	}
+
+	history, err := h.service.GetMessageHistory(c.Request().Context(), id, nickname)
+	if err != nil {
+		return c.JSON(changeErrorStatus(err), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, history)
+}
+
+// AddReactionHandler godoc
+// @Summary Add a reaction
+// @Description React to a message with an emoji. Each user reacts at most once with each emoji; connected clients get a reaction envelope with the new count.
+// @Tags Message
+// @Produce json
+// @Param id path string true "Message ID"
+// @Param emoji path string true "Emoji, URL encoded"
+// @Success 200 {object} models.Reaction
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Conflict"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /messages/{id}/reactions/{emoji} [put]
+func (h *MessageHandler) AddReactionHandler(c echo.Context) error {
+	return h.react(c, false)
+}
+
+// RemoveReactionHandler godoc
+// @Summary Remove a reaction
+// @Description Remove the emoji reaction of the logged in user from a message; connected clients get a reaction envelope with the new count.
+// @Tags Message
+// @Produce json
+// @Param id path string true "Message ID"
+// @Param emoji path string true "Emoji, URL encoded"
+// @Success 200 {object} models.Reaction
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /messages/{id}/reactions/{emoji} [delete]
+func (h *MessageHandler) RemoveReactionHandler(c echo.Context) error {
+	return h.react(c, true)
+}
+
+func (h *MessageHandler) react(c echo.Context, remove bool) error {
+	nickname, err := sessionNickname(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, err.Error())
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, "Invalid message id")
+	}
+	emoji, err := url.PathUnescape(c.Param("emoji"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, services.ErrInvalidEmoji.Error())
+	}
+
+	reaction, changed, err := h.service.React(c.Request().Context(), id, nickname, emoji, remove)
+	if err != nil {
+		return c.JSON(changeErrorStatus(err), err.Error())
+	}
+
+	if changed {
+		h.hub.Broadcast <- services.ReactionEnvelope(reaction)
+	}
+	return c.JSON(http.StatusOK, reaction)
+}
+
+// changeErrorStatus maps the errors of message changes to HTTP statuses.
+func changeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrEmptyMessage), errors.Is(err, services.ErrMessageTooLong),
+		errors.Is(err, services.ErrInvalidEmoji):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrMessageNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrMessageDeleted):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"github.com/LuccChagas/my-chat-app/internal/websocket"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type PresenceHandler struct {
+	hub *websocket.Hub
+}
+
+func NewPresenceHandler(hub *websocket.Hub) *PresenceHandler {
+	return &PresenceHandler{
+		hub: hub,
+	}
+}
+
+// GetPresenceHandler godoc
+// @Summary List online users
+// @Description List the connected users, on every server of the cluster, and whether they are online or idle. A user with several tabs open is listed once.
+// @Tags Presence
+// @Produce json
+// @Success 200 {array} websocket.Presence
+// @Failure 401 {string} string "Unauthorized"
+// @Router /presence [get]
+func (h *PresenceHandler) GetPresenceHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.hub.Online())
+}
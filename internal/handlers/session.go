@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+// sessionNickname returns the nickname of the logged in user.
+func sessionNickname(c echo.Context) (string, error) {
+	sess, err := session.Get("session", c)
+	if err != nil {
+		return "", err
+	}
+	nickname, ok := sess.Values["nickname"]
+	if !ok {
+		return "", errors.New("not logged in")
+	}
+	return fmt.Sprintf("%v", nickname), nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"strings"
 )
 
 type WsHandler struct {
@@ -21,8 +22,17 @@ func NewWsHandler(s *services.WsService, socket *socket.Hub) *WsHandler {
 }
 
 func (h *WsHandler) WsHandler(c echo.Context) error {
+	room := strings.ToLower(c.QueryParam("room"))
+	if room == "" {
+		room = socket.DefaultRoom
+	}
+	if !socket.ValidRoom(room) {
+		return c.JSON(http.StatusBadRequest, "Invalid room name")
+	}
+
 	upgrader := websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool { return true },
+		CheckOrigin:  func(r *http.Request) bool { return true },
+		Subprotocols: []string{socket.JSONSubprotocol},
 	}
 	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
@@ -43,16 +53,21 @@ func (h *WsHandler) WsHandler(c echo.Context) error {
 		Conn:     ws,
 		Send:     make(chan []byte, 256),
 		Nickname: nickname,
+		Room:     room,
+	}
+	// clients that did not negotiate the JSON protocol get plain text lines
+	if ws.Subprotocol() == socket.JSONSubprotocol {
+		client.Format = socket.FormatJSON
 	}
 
 	client.Hub.Register <- client
 
-	for _, msg := range client.Hub.Messages {
-		formattedMsg := fmt.Sprintf("[%s] %s: %s \n", msg.Timestamp.Format("15:04:05"), msg.Author, msg.Content)
-		client.Send <- []byte(formattedMsg)
+	ctx := context.Background()
+	// clients that page the history over REST ask for no replay
+	if c.QueryParam("history") != "false" {
+		h.service.SendHistory(ctx, client)
 	}
 
-	ctx := context.Background()
 	go h.service.ReadingPool(ctx, client)
 	go h.service.WritingPool(ctx, client)
 
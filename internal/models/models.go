@@ -1,9 +1,85 @@
 package models
 
-import "time"
+import (
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+	"github.com/google/uuid"
+	"time"
+)
 
+// Message is a room message. A deleted message is kept as a tombstone, with
+// Deleted set and an empty Content.
 type Message struct {
-	Timestamp time.Time
-	Content   string
-	Author    string
+	ID        uuid.UUID  `json:"id"`
+	Room      string     `json:"room"`
+	Timestamp time.Time  `json:"timestamp"`
+	Content   string     `json:"content"`
+	Author    string     `json:"author"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	Deleted   bool       `json:"deleted,omitempty"`
+	// Reactions is only filled when loading history.
+	Reactions []ReactionCount `json:"reactions,omitempty"`
+}
+
+// ReactionCount aggregates the reactions of one emoji on a message. Reacted
+// tells whether the user who loaded the message is one of them.
+type ReactionCount struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted,omitempty"`
+}
+
+// Reaction is a reaction added to or removed from a message, with the new
+// number of reactions of its emoji.
+type Reaction struct {
+	MessageID uuid.UUID `json:"message_id"`
+	Room      string    `json:"room"`
+	Nickname  string    `json:"nickname"`
+	Emoji     string    `json:"emoji"`
+	Removed   bool      `json:"removed,omitempty"`
+	Count     int       `json:"count"`
+}
+
+// MessageEdit is a previous version of a message, replaced by an edit or
+// the deletion made by EditedBy at EditedAt.
+type MessageEdit struct {
+	Content  string    `json:"content"`
+	EditedBy string    `json:"edited_by"`
+	EditedAt time.Time `json:"edited_at"`
+}
+
+type EditMessageRequest struct {
+	Content string `json:"content" validate:"required"`
+}
+
+type MessagePage struct {
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// DirectMessage belongs to the one-to-one channel of its author and To.
+type DirectMessage struct {
+	ID        uuid.UUID `json:"id"`
+	Channel   uuid.UUID `json:"channel"`
+	Timestamp time.Time `json:"timestamp"`
+	Content   string    `json:"content"`
+	Author    string    `json:"author"`
+	To        string    `json:"to"`
+}
+
+type DirectMessagePage struct {
+	Messages   []DirectMessage `json:"messages"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// DirectChannel is a conversation as seen by one of its participants.
+type DirectChannel struct {
+	ID          uuid.UUID      `json:"id"`
+	With        string         `json:"with"`
+	LastMessage *DirectMessage `json:"last_message,omitempty"`
+}
+
+type HealthStatus struct {
+	Status string     `json:"status"`
+	Bus    bus.Status `json:"bus"`
+	Error  string     `json:"error,omitempty"`
 }
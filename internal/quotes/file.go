@@ -0,0 +1,40 @@
+package quotes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+var symbolPattern = regexp.MustCompile(`^[a-z0-9^][a-z0-9._^-]*$`)
+
+// Files serves quotes from fixture files named "<symbol>.csv", in the stooq
+// CSV format, so the bot can run without network access.
+type Files struct {
+	dir string
+}
+
+func NewFiles(dir string) *Files {
+	return &Files{dir: dir}
+}
+
+func (f *Files) Quote(ctx context.Context, symbol string) (Quote, error) {
+	if !symbolPattern.MatchString(symbol) {
+		return Quote{}, fmt.Errorf("%w: %s", ErrUnknownSymbol, symbol)
+	}
+
+	file, err := os.Open(filepath.Join(f.dir, symbol+".csv"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Quote{}, fmt.Errorf("%w: %s", ErrUnknownSymbol, symbol)
+	}
+	if err != nil {
+		return Quote{}, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer file.Close()
+
+	return ParseCSV(file)
+}
@@ -0,0 +1,55 @@
+package quotes_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LuccChagas/my-chat-app/internal/quotes"
+)
+
+const fixturesDir = "../../fixtures/quotes"
+
+func TestFiles_Quote(t *testing.T) {
+	files := quotes.NewFiles(fixturesDir)
+
+	tests := []struct {
+		symbol    string
+		wantClose float64
+		wantErr   error
+	}{
+		{symbol: "aapl.us", wantClose: 236.85},
+		{symbol: "amzn.us", wantClose: 218.94},
+		{symbol: "googl.us", wantClose: 192.04},
+		{symbol: "msft.us", wantClose: 418.95},
+		{symbol: "nope.us", wantErr: quotes.ErrUnknownSymbol},
+		{symbol: "AAPL.US", wantErr: quotes.ErrUnknownSymbol},
+		{symbol: "../quotes/aapl.us", wantErr: quotes.ErrUnknownSymbol},
+		{symbol: "", wantErr: quotes.ErrUnknownSymbol},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			quote, err := files.Quote(context.Background(), tt.symbol)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantClose, quote.Close)
+			assert.Equal(t, "2025-01-10", quote.Date)
+			assert.NotZero(t, quote.Volume)
+		})
+	}
+}
+
+func TestFiles_QuoteMalformedFixture(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "bad.us.csv"), []byte("not a quote"), 0o600))
+
+	_, err := quotes.NewFiles(dir).Quote(context.Background(), "bad.us")
+	assert.ErrorIs(t, err, quotes.ErrMalformed)
+}
@@ -0,0 +1,86 @@
+package quotes
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownSymbol = errors.New("unknown symbol")
+	ErrTimeout       = errors.New("quote service timed out")
+	ErrUnavailable   = errors.New("quote service unavailable")
+	ErrMalformed     = errors.New("malformed quote")
+)
+
+// Quote is the latest trading day of a symbol as reported by stooq. Date and
+// Time are kept as sent, e.g. "2025-01-10" and "22:00:05".
+type Quote struct {
+	Symbol string  `json:"symbol"`
+	Date   string  `json:"date"`
+	Time   string  `json:"time"`
+	Open   float64 `json:"open"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	Volume int64   `json:"volume"`
+}
+
+// Change returns how much the price moved since the open.
+func (q Quote) Change() float64 {
+	return q.Close - q.Open
+}
+
+// ChangePercent returns Change as a percentage of the open.
+func (q Quote) ChangePercent() float64 {
+	if q.Open == 0 {
+		return 0
+	}
+	return q.Change() / q.Open * 100
+}
+
+// ParseCSV reads a quote in the stooq CSV format: a header line followed by
+// Symbol,Date,Time,Open,High,Low,Close,Volume.
+func ParseCSV(r io.Reader) (Quote, error) {
+	reader := csv.NewReader(r)
+	// the volume column is left out for some symbols
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return Quote{}, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if len(records) < 2 {
+		return Quote{}, fmt.Errorf("%w: incomplete CSV", ErrMalformed)
+	}
+
+	data := records[1]
+	if len(data) < 7 {
+		return Quote{}, fmt.Errorf("%w: unexpected CSV format", ErrMalformed)
+	}
+	if data[6] == "N/D" {
+		return Quote{}, fmt.Errorf("%w: %s", ErrUnknownSymbol, data[0])
+	}
+
+	quote := Quote{
+		Symbol: strings.ToUpper(data[0]),
+		Date:   data[1],
+		Time:   data[2],
+	}
+	prices := []*float64{&quote.Open, &quote.High, &quote.Low, &quote.Close}
+	for i, price := range prices {
+		if *price, err = strconv.ParseFloat(data[3+i], 64); err != nil {
+			return Quote{}, fmt.Errorf("%w: invalid price %q", ErrMalformed, data[3+i])
+		}
+	}
+	// indices and some funds have no volume
+	if len(data) > 7 && data[7] != "" && data[7] != "N/D" {
+		if quote.Volume, err = strconv.ParseInt(data[7], 10, 64); err != nil {
+			return Quote{}, fmt.Errorf("%w: invalid volume %q", ErrMalformed, data[7])
+		}
+	}
+
+	return quote, nil
+}
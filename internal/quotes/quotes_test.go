@@ -0,0 +1,98 @@
+package quotes_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/LuccChagas/my-chat-app/internal/quotes"
+)
+
+const csvHeader = "Symbol,Date,Time,Open,High,Low,Close,Volume\n"
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    quotes.Quote
+		wantErr error
+	}{
+		{
+			name: "quote",
+			csv:  csvHeader + "aapl.us,2025-01-10,22:00:05,240.01,240.16,233,236.85,61710856\n",
+			want: quotes.Quote{Symbol: "AAPL.US", Date: "2025-01-10", Time: "22:00:05",
+				Open: 240.01, High: 240.16, Low: 233, Close: 236.85, Volume: 61710856},
+		},
+		{
+			name: "missing volume column",
+			csv:  csvHeader + "^SPX,2025-01-10,22:00:05,5890.2,5890.2,5809.3,5827.04\n",
+			want: quotes.Quote{Symbol: "^SPX", Date: "2025-01-10", Time: "22:00:05",
+				Open: 5890.2, High: 5890.2, Low: 5809.3, Close: 5827.04},
+		},
+		{
+			name: "empty volume",
+			csv:  csvHeader + "^SPX,2025-01-10,22:00:05,5890.2,5890.2,5809.3,5827.04,\n",
+			want: quotes.Quote{Symbol: "^SPX", Date: "2025-01-10", Time: "22:00:05",
+				Open: 5890.2, High: 5890.2, Low: 5809.3, Close: 5827.04},
+		},
+		{
+			name: "N/D volume",
+			csv:  csvHeader + "^SPX,2025-01-10,22:00:05,5890.2,5890.2,5809.3,5827.04,N/D\n",
+			want: quotes.Quote{Symbol: "^SPX", Date: "2025-01-10", Time: "22:00:05",
+				Open: 5890.2, High: 5890.2, Low: 5809.3, Close: 5827.04},
+		},
+		{
+			name:    "N/D row",
+			csv:     csvHeader + "NOPE.US,N/D,N/D,N/D,N/D,N/D,N/D,N/D\n",
+			wantErr: quotes.ErrUnknownSymbol,
+		},
+		{
+			name:    "header only",
+			csv:     csvHeader,
+			wantErr: quotes.ErrMalformed,
+		},
+		{
+			name:    "empty",
+			csv:     "",
+			wantErr: quotes.ErrMalformed,
+		},
+		{
+			name:    "too few columns",
+			csv:     csvHeader + "AAPL.US,2025-01-10,22:00:05,240.01\n",
+			wantErr: quotes.ErrMalformed,
+		},
+		{
+			name:    "unbalanced quotes",
+			csv:     csvHeader + "\"AAPL.US,2025-01-10,22:00:05,240.01,240.16,233,236.85,61710856\n",
+			wantErr: quotes.ErrMalformed,
+		},
+		{
+			name:    "invalid price",
+			csv:     csvHeader + "AAPL.US,2025-01-10,22:00:05,240.01,240.16,abc,236.85,61710856\n",
+			wantErr: quotes.ErrMalformed,
+		},
+		{
+			name:    "invalid volume",
+			csv:     csvHeader + "AAPL.US,2025-01-10,22:00:05,240.01,240.16,233,236.85,lots\n",
+			wantErr: quotes.ErrMalformed,
+		},
+		{
+			name:    "html error page",
+			csv:     "<html><body>Service Unavailable</body></html>\n",
+			wantErr: quotes.ErrMalformed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := quotes.ParseCSV(strings.NewReader(tt.csv))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, quote)
+		})
+	}
+}
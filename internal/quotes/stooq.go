@@ -0,0 +1,47 @@
+package quotes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const stooqURLTemplate = "https://stooq.com/q/l/?s=%s&f=sd2t2ohlcv&h&e=csv"
+
+// Stooq fetches quotes from stooq.com.
+type Stooq struct {
+	client *http.Client
+}
+
+func NewStooq() *Stooq {
+	return &Stooq{
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *Stooq) Quote(ctx context.Context, symbol string) (Quote, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(stooqURLTemplate, url.QueryEscape(symbol)), nil)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return Quote{}, fmt.Errorf("%w: %v", ErrTimeout, err)
+		}
+		return Quote{}, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Quote{}, fmt.Errorf("%w: API returned status %d", ErrUnavailable, resp.StatusCode)
+	}
+
+	return ParseCSV(resp.Body)
+}
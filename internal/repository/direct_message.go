@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+)
+
+func (r *Repository) UpsertDMChannel(ctx context.Context, arg db.UpsertDMChannelParams) (db.DmChannel, error) {
+	channel, err := r.queries.UpsertDMChannel(ctx, arg)
+	if err != nil {
+		return db.DmChannel{}, err
+	}
+
+	return channel, nil
+}
+
+func (r *Repository) GetDMChannel(ctx context.Context, arg db.GetDMChannelParams) (db.DmChannel, error) {
+	channel, err := r.queries.GetDMChannel(ctx, arg)
+	if err != nil {
+		return db.DmChannel{}, err
+	}
+
+	return channel, nil
+}
+
+func (r *Repository) ListDMChannels(ctx context.Context, nickname string) ([]db.ListDMChannelsRow, error) {
+	channels, err := r.queries.ListDMChannels(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	return channels, nil
+}
+
+func (r *Repository) CreateDirectMessage(ctx context.Context, arg db.CreateDirectMessageParams) (db.DirectMessage, error) {
+	m, err := r.queries.CreateDirectMessage(ctx, arg)
+	if err != nil {
+		return db.DirectMessage{}, err
+	}
+
+	return m, nil
+}
+
+func (r *Repository) GetRecentDirectMessages(ctx context.Context, arg db.GetRecentDirectMessagesParams) ([]db.DirectMessage, error) {
+	messages, err := r.queries.GetRecentDirectMessages(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (r *Repository) GetDirectMessagesBefore(ctx context.Context, arg db.GetDirectMessagesBeforeParams) ([]db.DirectMessage, error) {
+	messages, err := r.queries.GetDirectMessagesBefore(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
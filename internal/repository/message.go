@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+	"github.com/google/uuid"
+)
+
+func (r *Repository) CreateMessage(ctx context.Context, message db.CreateMessageParams) (db.Message, error) {
+	m, err := r.queries.CreateMessage(ctx, message)
+	if err != nil {
+		return db.Message{}, err
+	}
+
+	return m, nil
+}
+
+func (r *Repository) GetRecentMessages(ctx context.Context, arg db.GetRecentMessagesParams) ([]db.Message, error) {
+	messages, err := r.queries.GetRecentMessages(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (r *Repository) GetMessagesBefore(ctx context.Context, arg db.GetMessagesBeforeParams) ([]db.Message, error) {
+	messages, err := r.queries.GetMessagesBefore(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (r *Repository) GetMessage(ctx context.Context, id uuid.UUID) (db.Message, error) {
+	m, err := r.queries.GetMessage(ctx, id)
+	if err != nil {
+		return db.Message{}, err
+	}
+
+	return m, nil
+}
+
+func (r *Repository) EditMessage(ctx context.Context, arg db.EditMessageParams) (db.Message, error) {
+	m, err := r.queries.EditMessage(ctx, arg)
+	if err != nil {
+		return db.Message{}, err
+	}
+
+	return m, nil
+}
+
+func (r *Repository) DeleteMessage(ctx context.Context, arg db.DeleteMessageParams) (db.Message, error) {
+	m, err := r.queries.DeleteMessage(ctx, arg)
+	if err != nil {
+		return db.Message{}, err
+	}
+
+	return m, nil
+}
+
+func (r *Repository) ListMessageEdits(ctx context.Context, messageID uuid.UUID) ([]db.MessageEdit, error) {
+	edits, err := r.queries.ListMessageEdits(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return edits, nil
+}
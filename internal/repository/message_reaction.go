@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+)
+
+func (r *Repository) AddReaction(ctx context.Context, arg db.AddReactionParams) (int64, error) {
+	return r.queries.AddReaction(ctx, arg)
+}
+
+func (r *Repository) RemoveReaction(ctx context.Context, arg db.RemoveReactionParams) (int64, error) {
+	return r.queries.RemoveReaction(ctx, arg)
+}
+
+func (r *Repository) CountReactions(ctx context.Context, arg db.CountReactionsParams) (int64, error) {
+	return r.queries.CountReactions(ctx, arg)
+}
+
+func (r *Repository) ListReactionCounts(ctx context.Context, arg db.ListReactionCountsParams) ([]db.ListReactionCountsRow, error) {
+	counts, err := r.queries.ListReactionCounts(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
@@ -23,4 +23,21 @@ type RepositoryInterface interface {
 	GetAllUsers(ctx context.Context) ([]db.User, error)
 	GetUser(ctx context.Context, id uuid.UUID) (db.User, error)
 	GetUserByNickname(ctx context.Context, nickname string) (db.User, error)
+	CreateMessage(ctx context.Context, message db.CreateMessageParams) (db.Message, error)
+	GetRecentMessages(ctx context.Context, arg db.GetRecentMessagesParams) ([]db.Message, error)
+	GetMessagesBefore(ctx context.Context, arg db.GetMessagesBeforeParams) ([]db.Message, error)
+	GetMessage(ctx context.Context, id uuid.UUID) (db.Message, error)
+	EditMessage(ctx context.Context, arg db.EditMessageParams) (db.Message, error)
+	DeleteMessage(ctx context.Context, arg db.DeleteMessageParams) (db.Message, error)
+	ListMessageEdits(ctx context.Context, messageID uuid.UUID) ([]db.MessageEdit, error)
+	AddReaction(ctx context.Context, arg db.AddReactionParams) (int64, error)
+	RemoveReaction(ctx context.Context, arg db.RemoveReactionParams) (int64, error)
+	CountReactions(ctx context.Context, arg db.CountReactionsParams) (int64, error)
+	ListReactionCounts(ctx context.Context, arg db.ListReactionCountsParams) ([]db.ListReactionCountsRow, error)
+	UpsertDMChannel(ctx context.Context, arg db.UpsertDMChannelParams) (db.DmChannel, error)
+	GetDMChannel(ctx context.Context, arg db.GetDMChannelParams) (db.DmChannel, error)
+	ListDMChannels(ctx context.Context, nickname string) ([]db.ListDMChannelsRow, error)
+	CreateDirectMessage(ctx context.Context, arg db.CreateDirectMessageParams) (db.DirectMessage, error)
+	GetRecentDirectMessages(ctx context.Context, arg db.GetRecentDirectMessagesParams) ([]db.DirectMessage, error)
+	GetDirectMessagesBefore(ctx context.Context, arg db.GetDirectMessagesBeforeParams) ([]db.DirectMessage, error)
 }
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+)
+
+func (r *Repository) CreateStockAlert(ctx context.Context, arg db.CreateStockAlertParams) (db.StockAlert, error) {
+	alert, err := r.queries.CreateStockAlert(ctx, arg)
+	if err != nil {
+		return db.StockAlert{}, err
+	}
+
+	return alert, nil
+}
+
+func (r *Repository) CountStockAlertsByOwner(ctx context.Context, owner string) (int64, error) {
+	return r.queries.CountStockAlertsByOwner(ctx, owner)
+}
+
+func (r *Repository) ListStockAlertsByOwner(ctx context.Context, owner string) ([]db.StockAlert, error) {
+	alerts, err := r.queries.ListStockAlertsByOwner(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return alerts, nil
+}
+
+func (r *Repository) ListStockAlerts(ctx context.Context) ([]db.StockAlert, error) {
+	alerts, err := r.queries.ListStockAlerts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return alerts, nil
+}
+
+// DeleteStockAlert returns how many alerts were deleted: 0 when the id does
+// not exist or belongs to someone else.
+func (r *Repository) DeleteStockAlert(ctx context.Context, arg db.DeleteStockAlertParams) (int64, error) {
+	return r.queries.DeleteStockAlert(ctx, arg)
+}
+
+// TriggerStockAlert marks an alert as notified and returns 0 when it
+// already was, so only one bot instance sends the notification.
+func (r *Repository) TriggerStockAlert(ctx context.Context, id int64) (int64, error) {
+	return r.queries.TriggerStockAlert(ctx, id)
+}
+
+func (r *Repository) RearmStockAlert(ctx context.Context, id int64) error {
+	return r.queries.RearmStockAlert(ctx, id)
+}
@@ -0,0 +1,30 @@
+package routers
+
+import (
+	"github.com/LuccChagas/my-chat-app/internal/handlers"
+	"github.com/labstack/echo/v4"
+)
+
+// BotRouter serves the HTTP endpoints of cmd/bot, used by the orchestrator
+// and by Prometheus.
+type BotRouter struct {
+	Bot handlers.BotHandlerInterface
+}
+
+func NewBotRouter(bot handlers.BotHandlerInterface) *BotRouter {
+	return &BotRouter{
+		Bot: bot,
+	}
+}
+
+// Echo returns the server of the bot endpoints, ready to be started.
+func (router *BotRouter) Echo() *echo.Echo {
+	e := echo.New()
+	e.HideBanner = true
+
+	e.GET("/healthz", router.Bot.GetLiveHandler)
+	e.GET("/readyz", router.Bot.GetReadyHandler)
+	e.GET("/metrics", router.Bot.GetMetricsHandler)
+
+	return e
+}
@@ -16,6 +16,29 @@ func (router *Router) Endpoints(e *echo.Echo) {
 	user.GET("/all", router.User.GetAllUsersHandler)
 	user.POST("/auth", router.User.UserLoginHandler)
 
+	// rooms routes
+	rooms := e.Group("/rooms", middleware.AuthMiddleware)
+	rooms.GET("/:room/messages", router.Message.GetRoomMessagesHandler)
+
+	// message changes, allowed to the author and to moderators, and reactions
+	messages := e.Group("/messages", middleware.AuthMiddleware)
+	messages.PUT("/:id", router.Message.EditMessageHandler)
+	messages.DELETE("/:id", router.Message.DeleteMessageHandler)
+	messages.GET("/:id/history", router.Message.GetMessageHistoryHandler)
+	messages.PUT("/:id/reactions/:emoji", router.Message.AddReactionHandler)
+	messages.DELETE("/:id/reactions/:emoji", router.Message.RemoveReactionHandler)
+
+	// direct messages routes, always scoped to the session user
+	dm := e.Group("/dm", middleware.AuthMiddleware)
+	dm.GET("", router.Direct.GetDirectChannelsHandler)
+	dm.GET("/:nickname/messages", router.Direct.GetDirectMessagesHandler)
+
+	// connected users, merged across the cluster nodes
+	e.GET("/presence", router.Presence.GetPresenceHandler, middleware.AuthMiddleware)
+
+	// health check, used by the load balancer and the orchestrator
+	e.GET("/healthz", router.Health.GetHealthHandler)
+
 	// websocket route
 	e.GET("/ws", router.Ws.WsHandler, middleware.AuthMiddleware)
 
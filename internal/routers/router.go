@@ -20,18 +20,30 @@ import (
 )
 
 type Router struct {
-	User handlers.UserHandlerInterface
-	Ws   handlers.WsHandlerInterface
+	User     handlers.UserHandlerInterface
+	Message  handlers.MessageHandlerInterface
+	Direct   handlers.DirectMessageHandlerInterface
+	Presence handlers.PresenceHandlerInterface
+	Health   handlers.HealthHandlerInterface
+	Ws       handlers.WsHandlerInterface
 }
 
 func NewRouter(
 	user handlers.UserHandlerInterface,
+	message handlers.MessageHandlerInterface,
+	direct handlers.DirectMessageHandlerInterface,
+	presence handlers.PresenceHandlerInterface,
+	health handlers.HealthHandlerInterface,
 	ws handlers.WsHandlerInterface,
 
 ) *Router {
 	return &Router{
-		User: user,
-		Ws:   ws,
+		User:     user,
+		Message:  message,
+		Direct:   direct,
+		Presence: presence,
+		Health:   health,
+		Ws:       ws,
 	}
 }
 
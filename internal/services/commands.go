@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LuccChagas/my-chat-app/internal/bot"
+	"github.com/LuccChagas/my-chat-app/internal/commands"
+	ws "github.com/LuccChagas/my-chat-app/internal/websocket"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	defaultDeadLetterCount = 10
+	maxDeadLetterCount     = 50
+)
+
+func (s *WsService) registerCommands() {
+	s.commands.Register(commands.Command{
+		Spec: commands.Spec{
+			Name:    "join",
+			Args:    "<room>",
+			Help:    "Join a room",
+			MinArgs: 1,
+			MaxArgs: 1,
+		},
+		Handler: func(ctx context.Context, inv commands.Invocation) error {
+			s.joinRoom(ctx, inv.Client, strings.ToLower(inv.Args[0]))
+			return nil
+		},
+	})
+
+	s.commands.Register(commands.Command{
+		Spec: commands.Spec{
+			Name: "leave",
+			Help: "Go back to the general room",
+		},
+		Handler: func(ctx context.Context, inv commands.Invocation) error {
+			s.joinRoom(ctx, inv.Client, ws.DefaultRoom)
+			return nil
+		},
+	})
+
+	s.commands.Register(commands.Command{
+		Spec: commands.Spec{
+			Name:    "dm",
+			Args:    "<nickname> <message>",
+			Help:    "Send a private message to a user",
+			MinArgs: 2,
+			MaxArgs: -1,
+		},
+		Handler: func(ctx context.Context, inv commands.Invocation) error {
+			return s.sendDirect(ctx, inv.Client, "", inv.Args[0], strings.Join(inv.Args[1:], " "))
+		},
+	})
+
+	s.commands.Register(commands.Command{
+		Spec: commands.Spec{
+			Name:    "dlq",
+			Args:    "[replay] [count]",
+			Help:    "List or replay the bot requests that kept failing (admins only)",
+			MaxArgs: 2,
+		},
+		Handler: s.deadLetters,
+	})
+
+	for _, spec := range bot.Specs() {
+		s.commands.Register(commands.Command{Spec: spec, Handler: s.forwardToBot})
+	}
+}
+
+// forwardToBot publishes a bot command and tells the room it is being processed.
+func (s *WsService) forwardToBot(ctx context.Context, inv commands.Invocation) error {
+	if !s.takeBotToken(inv) {
+		return nil
+	}
+
+	// registered before publishing so a fast reply cannot beat it
+	s.awaitBotReply(inv)
+
+	err := s.PublishBotRequest(ctx, bot.Request{
+		ID:        inv.ID,
+		Command:   inv.Name,
+		Args:      inv.Args,
+		Room:      inv.Client.Room,
+		Requester: inv.Client.Nickname,
+	})
+	if err != nil {
+		s.pending.resolve(inv.ID)
+		s.giveBackBotToken(inv)
+		return fmt.Errorf("could not send /%s to the bot, try again later", inv.Name)
+	}
+
+	confirmationMsg := fmt.Sprintf("Processing command: /%s %s", inv.Name, strings.Join(inv.Args, " "))
+	inv.Client.Hub.Broadcast <- ws.SystemEnvelope(inv.Client.Room, confirmationMsg)
+	return nil
+}
+
+// takeBotToken charges a bot command to the requester and to the room, and
+// tells the requester to slow down when either is over its limit.
+func (s *WsService) takeBotToken(inv commands.Invocation) bool {
+	now := time.Now()
+	client := inv.Client
+
+	ok, wait := s.userLimits.take(client.Nickname, now)
+	if ok {
+		if ok, wait = s.roomLimits.take(client.Room, now); !ok {
+			s.userLimits.giveBack(client.Nickname, now)
+		}
+	}
+	if !ok {
+		client.SendEnvelope(ws.SystemEnvelope(client.Room, fmt.Sprintf(
+			"Slow down! Too many bot commands, try /%s again in %s", inv.Name, (wait+time.Second-1).Truncate(time.Second))))
+		return false
+	}
+	return true
+}
+
+func (s *WsService) giveBackBotToken(inv commands.Invocation) {
+	now := time.Now()
+	s.userLimits.giveBack(inv.Client.Nickname, now)
+	s.roomLimits.giveBack(inv.Client.Room, now)
+}
+
+// deadLetters lists the dead-lettered bot requests to the admin who asked,
+// or publishes them again with "/dlq replay".
+func (s *WsService) deadLetters(ctx context.Context, inv commands.Invocation) error {
+	if !s.admins[inv.Client.Nickname] {
+		return fmt.Errorf("/%s is reserved to admins", inv.Name)
+	}
+	deadLetters, ok := s.bus.(bus.DeadLetters)
+	if !ok {
+		return fmt.Errorf("the message bus does not keep failed requests")
+	}
+
+	args := inv.Args
+	replay := len(args) > 0 && args[0] == "replay"
+	if replay {
+		args = args[1:]
+	}
+	count := defaultDeadLetterCount
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > maxDeadLetterCount {
+			return fmt.Errorf("count must be between 1 and %d", maxDeadLetterCount)
+		}
+		count = n
+	}
+
+	if replay {
+		n, err := deadLetters.Replay(ctx, bot.RequestTopic, count)
+		if err != nil {
+			log.Printf("Error replaying dead letters: %v", err)
+			return fmt.Errorf("replayed %d requests before failing, try again later", n)
+		}
+		inv.Client.SendEnvelope(ws.SystemEnvelope(inv.Client.Room, fmt.Sprintf("Replayed %d failed bot requests", n)))
+		return nil
+	}
+
+	msgs, err := deadLetters.DeadLetters(ctx, bot.RequestTopic, count)
+	if err != nil {
+		log.Printf("Error reading dead letters: %v", err)
+		return fmt.Errorf("could not read the failed requests, try again later")
+	}
+	if len(msgs) == 0 {
+		inv.Client.SendEnvelope(ws.SystemEnvelope(inv.Client.Room, "No failed bot requests"))
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Failed bot requests:")
+	for i, msg := range msgs {
+		sb.WriteString(fmt.Sprintf("\n%d. %s", i+1, describeDeadLetter(msg)))
+	}
+	inv.Client.SendEnvelope(ws.SystemEnvelope(inv.Client.Room, sb.String()))
+	return nil
+}
+
+func describeDeadLetter(msg bus.Message) string {
+	var req bot.Request
+	if err := json.Unmarshal(msg.Body, &req); err != nil || req.Command == "" {
+		return fmt.Sprintf("%q after %d attempts: %s", msg.Body, bus.Attempt(msg), msg.Headers[bus.ErrorHeader])
+	}
+	return fmt.Sprintf("/%s %s by %s in #%s after %d attempts: %s",
+		req.Command, strings.Join(req.Args, " "), req.Requester, req.Room, bus.Attempt(msg), msg.Headers[bus.ErrorHeader])
+}
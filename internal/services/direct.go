@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+	"github.com/LuccChagas/my-chat-app/internal/models"
+	"github.com/LuccChagas/my-chat-app/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownUser   = errors.New("unknown user")
+	ErrSelfMessage   = errors.New("cannot send a direct message to yourself")
+	ErrEmptyNickname = errors.New("missing nickname")
+)
+
+// DirectMessageService stores the one-to-one conversations. Every user pair
+// has one channel, and a user can only reach the channels they are part of
+// since channels are always looked up from the requester's nickname.
+type DirectMessageService struct {
+	repository repository.RepositoryInterface
+}
+
+func NewDirectMessageService(repository repository.RepositoryInterface) *DirectMessageService {
+	return &DirectMessageService{
+		repository: repository,
+	}
+}
+
+// channelMembers orders a pair of nicknames the way dm_channels stores it.
+func channelMembers(a, b string) (string, string) {
+	if a < b {
+		return a, b
+	}
+	return b, a
+}
+
+// SendDirectMessage stores content in the channel of from and to, creating
+// the channel on the first message.
+func (s *DirectMessageService) SendDirectMessage(ctx context.Context, from, to, content string) (models.DirectMessage, error) {
+	if to == "" {
+		return models.DirectMessage{}, ErrEmptyNickname
+	}
+	if to == from {
+		return models.DirectMessage{}, ErrSelfMessage
+	}
+	if _, err := s.repository.GetUserByNickname(ctx, to); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.DirectMessage{}, ErrUnknownUser
+		}
+		return models.DirectMessage{}, err
+	}
+
+	userA, userB := channelMembers(from, to)
+	channel, err := s.repository.UpsertDMChannel(ctx, db.UpsertDMChannelParams{
+		ID:    uuid.New(),
+		UserA: userA,
+		UserB: userB,
+	})
+	if err != nil {
+		return models.DirectMessage{}, err
+	}
+
+	message, err := s.repository.CreateDirectMessage(ctx, db.CreateDirectMessageParams{
+		ID:        uuid.New(),
+		ChannelID: channel.ID,
+		Author:    from,
+		Content:   content,
+	})
+	if err != nil {
+		return models.DirectMessage{}, err
+	}
+
+	return toDirectMessageModel(message, to), nil
+}
+
+// ListChannels returns the conversations of nickname, most recent first.
+func (s *DirectMessageService) ListChannels(ctx context.Context, nickname string) ([]models.DirectChannel, error) {
+	rows, err := s.repository.ListDMChannels(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := make([]models.DirectChannel, len(rows))
+	for i, row := range rows {
+		with := row.UserA
+		if with == nickname {
+			with = row.UserB
+		}
+		channels[i] = models.DirectChannel{ID: row.ID, With: with}
+
+		if row.LastID.Valid {
+			to := with
+			if row.LastAuthor.String == with {
+				to = nickname
+			}
+			channels[i].LastMessage = &models.DirectMessage{
+				ID:        row.LastID.UUID,
+				Channel:   row.ID,
+				Timestamp: row.LastCreatedAt.Time,
+				Content:   row.LastContent.String,
+				Author:    row.LastAuthor.String,
+				To:        to,
+			}
+		}
+	}
+
+	return channels, nil
+}
+
+// GetDirectMessages returns one page of the conversation between nickname
+// and with, paged like GetMessages. Users who never talked get an empty page.
+func (s *DirectMessageService) GetDirectMessages(ctx context.Context, nickname, with, cursor string, limit int) (models.DirectMessagePage, error) {
+	if with == "" {
+		return models.DirectMessagePage{}, ErrEmptyNickname
+	}
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	userA, userB := channelMembers(nickname, with)
+	channel, err := s.repository.GetDMChannel(ctx, db.GetDMChannelParams{UserA: userA, UserB: userB})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.DirectMessagePage{Messages: []models.DirectMessage{}}, nil
+		}
+		return models.DirectMessagePage{}, err
+	}
+
+	// one extra row tells whether an older page exists
+	var messages []db.DirectMessage
+	if cursor == "" {
+		messages, err = s.repository.GetRecentDirectMessages(ctx, db.GetRecentDirectMessagesParams{
+			ChannelID: channel.ID,
+			Limit:     int32(limit + 1),
+		})
+	} else {
+		createdAt, id, decodeErr := DecodeCursor(cursor)
+		if decodeErr != nil {
+			return models.DirectMessagePage{}, decodeErr
+		}
+		messages, err = s.repository.GetDirectMessagesBefore(ctx, db.GetDirectMessagesBeforeParams{
+			ChannelID: channel.ID,
+			CreatedAt: createdAt,
+			ID:        id,
+			RowLimit:  int32(limit + 1),
+		})
+	}
+	if err != nil {
+		return models.DirectMessagePage{}, err
+	}
+
+	page := models.DirectMessagePage{}
+	if len(messages) > limit {
+		messages = messages[:limit]
+		oldest := messages[limit-1]
+		page.NextCursor = EncodeCursor(oldest.CreatedAt, oldest.ID)
+	}
+
+	page.Messages = make([]models.DirectMessage, len(messages))
+	for i, message := range messages {
+		to := with
+		if message.Author == with {
+			to = nickname
+		}
+		page.Messages[len(messages)-1-i] = toDirectMessageModel(message, to)
+	}
+
+	return page, nil
+}
+
+func toDirectMessageModel(message db.DirectMessage, to string) models.DirectMessage {
+	return models.DirectMessage{
+		ID:        message.ID,
+		Channel:   message.ChannelID,
+		Timestamp: message.CreatedAt,
+		Content:   message.Content,
+		Author:    message.Author,
+		To:        to,
+	}
+}
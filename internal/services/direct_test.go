@@ -0,0 +1,125 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+	"github.com/LuccChagas/my-chat-app/internal/services"
+)
+
+func TestSendDirectMessage_Success(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewDirectMessageService(fakeRepo)
+	channel := db.DmChannel{ID: uuid.New(), UserA: "alice", UserB: "bob"}
+
+	fakeRepo.On("GetUserByNickname", mock.Anything, "alice").Return(db.User{NickName: "alice"}, nil)
+	fakeRepo.
+		On("UpsertDMChannel", mock.Anything, mock.Anything).
+		Return(channel, nil).
+		Run(func(args mock.Arguments) {
+			arg := args.Get(1).(db.UpsertDMChannelParams)
+			// the pair is stored in order whoever writes first
+			assert.Equal(t, "alice", arg.UserA)
+			assert.Equal(t, "bob", arg.UserB)
+		})
+	fakeRepo.
+		On("CreateDirectMessage", mock.Anything, mock.Anything).
+		Return(db.DirectMessage{ID: uuid.New(), ChannelID: channel.ID, Author: "bob", Content: "hi", CreatedAt: time.Now()}, nil)
+
+	message, err := svc.SendDirectMessage(context.Background(), "bob", "alice", "hi")
+	assert.NoError(t, err)
+	assert.Equal(t, channel.ID, message.Channel)
+	assert.Equal(t, "bob", message.Author)
+	assert.Equal(t, "alice", message.To)
+	fakeRepo.AssertExpectations(t)
+}
+
+func TestSendDirectMessage_UnknownUser(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewDirectMessageService(fakeRepo)
+
+	fakeRepo.On("GetUserByNickname", mock.Anything, "ghost").Return(db.User{}, sql.ErrNoRows)
+
+	_, err := svc.SendDirectMessage(context.Background(), "bob", "ghost", "hi")
+	assert.ErrorIs(t, err, services.ErrUnknownUser)
+	fakeRepo.AssertNotCalled(t, "CreateDirectMessage", mock.Anything, mock.Anything)
+}
+
+func TestSendDirectMessage_ToSelf(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewDirectMessageService(fakeRepo)
+
+	_, err := svc.SendDirectMessage(context.Background(), "bob", "bob", "hi")
+	assert.ErrorIs(t, err, services.ErrSelfMessage)
+}
+
+func TestListChannels(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewDirectMessageService(fakeRepo)
+	withMessage := uuid.New()
+
+	fakeRepo.On("ListDMChannels", mock.Anything, "bob").Return([]db.ListDMChannelsRow{
+		{
+			ID: withMessage, UserA: "alice", UserB: "bob",
+			LastID:        uuid.NullUUID{UUID: uuid.New(), Valid: true},
+			LastAuthor:    sql.NullString{String: "alice", Valid: true},
+			LastContent:   sql.NullString{String: "hello", Valid: true},
+			LastCreatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		},
+		{ID: uuid.New(), UserA: "bob", UserB: "carol"},
+	}, nil)
+
+	channels, err := svc.ListChannels(context.Background(), "bob")
+	assert.NoError(t, err)
+	assert.Len(t, channels, 2)
+	assert.Equal(t, "alice", channels[0].With)
+	assert.Equal(t, "hello", channels[0].LastMessage.Content)
+	assert.Equal(t, "bob", channels[0].LastMessage.To)
+	assert.Equal(t, "carol", channels[1].With)
+	assert.Nil(t, channels[1].LastMessage)
+}
+
+func TestGetDirectMessages_OnlyOwnChannel(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewDirectMessageService(fakeRepo)
+	channel := db.DmChannel{ID: uuid.New(), UserA: "alice", UserB: "bob"}
+	now := time.Now()
+
+	fakeRepo.On("GetDMChannel", mock.Anything, db.GetDMChannelParams{UserA: "alice", UserB: "bob"}).Return(channel, nil)
+	fakeRepo.
+		On("GetRecentDirectMessages", mock.Anything, db.GetRecentDirectMessagesParams{ChannelID: channel.ID, Limit: 3}).
+		Return([]db.DirectMessage{
+			{ID: uuid.New(), ChannelID: channel.ID, Author: "alice", Content: "3", CreatedAt: now},
+			{ID: uuid.New(), ChannelID: channel.ID, Author: "bob", Content: "2", CreatedAt: now.Add(-time.Second)},
+			{ID: uuid.New(), ChannelID: channel.ID, Author: "alice", Content: "1", CreatedAt: now.Add(-2 * time.Second)},
+		}, nil)
+
+	page, err := svc.GetDirectMessages(context.Background(), "bob", "alice", "", 2)
+	assert.NoError(t, err)
+	assert.Len(t, page.Messages, 2)
+	// oldest first
+	assert.Equal(t, "2", page.Messages[0].Content)
+	assert.Equal(t, "alice", page.Messages[0].To)
+	assert.Equal(t, "3", page.Messages[1].Content)
+	assert.Equal(t, "bob", page.Messages[1].To)
+	assert.NotEmpty(t, page.NextCursor)
+}
+
+func TestGetDirectMessages_NoChannel(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewDirectMessageService(fakeRepo)
+
+	fakeRepo.On("GetDMChannel", mock.Anything, db.GetDMChannelParams{UserA: "bob", UserB: "carol"}).Return(db.DmChannel{}, sql.ErrNoRows)
+
+	page, err := svc.GetDirectMessages(context.Background(), "carol", "bob", "", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, page.Messages)
+	fakeRepo.AssertNotCalled(t, "GetRecentDirectMessages", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+	"github.com/LuccChagas/my-chat-app/internal/models"
+	"github.com/google/uuid"
+	"strings"
+)
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	ErrMessageDeleted  = errors.New("message was deleted")
+	ErrNotAllowed      = errors.New("only the author or a moderator can change this message")
+	ErrEmptyMessage    = errors.New("message is empty")
+	ErrMessageTooLong  = fmt.Errorf("messages are limited to %d bytes", maxMessageSize)
+)
+
+// SetModerators sets the users allowed to edit and delete the messages of
+// everyone.
+func (s *MessageService) SetModerators(nicknames []string) {
+	s.moderators = make(map[string]bool, len(nicknames))
+	for _, nickname := range nicknames {
+		s.moderators[nickname] = true
+	}
+}
+
+// EditMessage replaces the content of a message, keeping the previous one
+// in its edit history.
+func (s *MessageService) EditMessage(ctx context.Context, id uuid.UUID, editor, content string) (models.Message, error) {
+	if strings.TrimSpace(content) == "" {
+		return models.Message{}, ErrEmptyMessage
+	}
+	if len(content) > maxMessageSize {
+		return models.Message{}, ErrMessageTooLong
+	}
+	if err := s.authorizeChange(ctx, id, editor); err != nil {
+		return models.Message{}, err
+	}
+
+	message, err := s.repository.EditMessage(ctx, db.EditMessageParams{
+		EditedBy: editor,
+		ID:       id,
+		Content:  content,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// deleted since it was authorized
+		return models.Message{}, ErrMessageDeleted
+	}
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	return toMessageModel(message), nil
+}
+
+// DeleteMessage turns a message into a tombstone. Its last content is kept
+// in the edit history.
+func (s *MessageService) DeleteMessage(ctx context.Context, id uuid.UUID, actor string) (models.Message, error) {
+	if err := s.authorizeChange(ctx, id, actor); err != nil {
+		return models.Message{}, err
+	}
+
+	message, err := s.repository.DeleteMessage(ctx, db.DeleteMessageParams{
+		DeletedBy: actor,
+		ID:        id,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Message{}, ErrMessageDeleted
+	}
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	return toMessageModel(message), nil
+}
+
+// GetMessageHistory lists the previous versions of a message, oldest first.
+// Since it reveals deleted content, only the author and moderators see it.
+func (s *MessageService) GetMessageHistory(ctx context.Context, id uuid.UUID, actor string) ([]models.MessageEdit, error) {
+	if _, err := s.authorize(ctx, id, actor); err != nil {
+		return nil, err
+	}
+
+	edits, err := s.repository.ListMessageEdits(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]models.MessageEdit, len(edits))
+	for i, edit := range edits {
+		history[i] = models.MessageEdit{
+			Content:  edit.Content,
+			EditedBy: edit.EditedBy,
+			EditedAt: edit.EditedAt,
+		}
+	}
+	return history, nil
+}
+
+// authorize loads a message that actor may change, tombstones included.
+func (s *MessageService) authorize(ctx context.Context, id uuid.UUID, actor string) (db.Message, error) {
+	message, err := s.repository.GetMessage(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return db.Message{}, ErrMessageNotFound
+	}
+	if err != nil {
+		return db.Message{}, err
+	}
+	if message.Author != actor && !s.moderators[actor] {
+		return db.Message{}, ErrNotAllowed
+	}
+
+	return message, nil
+}
+
+// authorizeChange checks that actor may change a message that was not
+// deleted.
+func (s *MessageService) authorizeChange(ctx context.Context, id uuid.UUID, actor string) error {
+	message, err := s.authorize(ctx, id, actor)
+	if err != nil {
+		return err
+	}
+	if message.DeletedAt.Valid {
+		return ErrMessageDeleted
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+	"github.com/LuccChagas/my-chat-app/internal/services"
+)
+
+func TestEditMessage_ByAuthor(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewMessageService(fakeRepo)
+	stored := db.Message{ID: uuid.New(), Room: "general", Author: "alice", Content: "helo", CreatedAt: time.Now()}
+	edited := stored
+	edited.Content = "hello"
+	edited.EditedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	fakeRepo.On("GetMessage", mock.Anything, stored.ID).Return(stored, nil)
+	fakeRepo.
+		On("EditMessage", mock.Anything, db.EditMessageParams{EditedBy: "alice", ID: stored.ID, Content: "hello"}).
+		Return(edited, nil)
+
+	message, err := svc.EditMessage(context.Background(), stored.ID, "alice", "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", message.Content)
+	assert.NotNil(t, message.EditedAt)
+	assert.False(t, message.Deleted)
+}
+
+func TestEditMessage_NotAllowed(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewMessageService(fakeRepo)
+	stored := db.Message{ID: uuid.New(), Room: "general", Author: "alice", Content: "hi", CreatedAt: time.Now()}
+
+	fakeRepo.On("GetMessage", mock.Anything, stored.ID).Return(stored, nil)
+
+	_, err := svc.EditMessage(context.Background(), stored.ID, "bob", "hacked")
+	assert.ErrorIs(t, err, services.ErrNotAllowed)
+	_, err = svc.DeleteMessage(context.Background(), stored.ID, "bob")
+	assert.ErrorIs(t, err, services.ErrNotAllowed)
+	fakeRepo.AssertNotCalled(t, "EditMessage", mock.Anything, mock.Anything)
+	fakeRepo.AssertNotCalled(t, "DeleteMessage", mock.Anything, mock.Anything)
+}
+
+func TestEditMessage_InvalidContent(t *testing.T) {
+	svc := services.NewMessageService(new(FakeRepository))
+
+	_, err := svc.EditMessage(context.Background(), uuid.New(), "alice", "  ")
+	assert.ErrorIs(t, err, services.ErrEmptyMessage)
+	_, err = svc.EditMessage(context.Background(), uuid.New(), "alice", string(make([]byte, 281)))
+	assert.ErrorIs(t, err, services.ErrMessageTooLong)
+}
+
+func TestEditMessage_Unknown(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewMessageService(fakeRepo)
+	id := uuid.New()
+
+	fakeRepo.On("GetMessage", mock.Anything, id).Return(db.Message{}, sql.ErrNoRows)
+
+	_, err := svc.EditMessage(context.Background(), id, "alice", "hello")
+	assert.ErrorIs(t, err, services.ErrMessageNotFound)
+}
+
+func TestDeleteMessage_ByModerator(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewMessageService(fakeRepo)
+	svc.SetModerators([]string{"mod"})
+	stored := db.Message{ID: uuid.New(), Room: "general", Author: "alice", Content: "spam", CreatedAt: time.Now()}
+	tombstone := stored
+	tombstone.Content = ""
+	tombstone.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	tombstone.DeletedBy = sql.NullString{String: "mod", Valid: true}
+
+	fakeRepo.On("GetMessage", mock.Anything, stored.ID).Return(stored, nil)
+	fakeRepo.
+		On("DeleteMessage", mock.Anything, db.DeleteMessageParams{DeletedBy: "mod", ID: stored.ID}).
+		Return(tombstone, nil)
+
+	message, err := svc.DeleteMessage(context.Background(), stored.ID, "mod")
+	assert.NoError(t, err)
+	assert.True(t, message.Deleted)
+	assert.Empty(t, message.Content)
+}
+
+func TestEditMessage_Tombstone(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewMessageService(fakeRepo)
+	stored := db.Message{
+		ID: uuid.New(), Room: "general", Author: "alice", CreatedAt: time.Now(),
+		DeletedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+
+	fakeRepo.On("GetMessage", mock.Anything, stored.ID).Return(stored, nil)
+	fakeRepo.
+		On("ListMessageEdits", mock.Anything, stored.ID).
+		Return([]db.MessageEdit{{MessageID: stored.ID, Content: "oops", EditedBy: "alice", EditedAt: time.Now()}}, nil)
+
+	_, err := svc.EditMessage(context.Background(), stored.ID, "alice", "hello")
+	assert.ErrorIs(t, err, services.ErrMessageDeleted)
+
+	// the author still sees what the message said
+	history, err := svc.GetMessageHistory(context.Background(), stored.ID, "alice")
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, "oops", history[0].Content)
+
+	_, err = svc.GetMessageHistory(context.Background(), stored.ID, "bob")
+	assert.ErrorIs(t, err, services.ErrNotAllowed)
+}
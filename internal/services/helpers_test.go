@@ -0,0 +1,225 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/mock"
+
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+	"github.com/LuccChagas/my-chat-app/internal/repository"
+	"github.com/LuccChagas/my-chat-app/internal/services"
+	ws "github.com/LuccChagas/my-chat-app/internal/websocket"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+)
+
+type FakeRepository struct {
+	mock.Mock
+}
+
+func (r *FakeRepository) CreateUser(ctx context.Context, arg db.CreateUsersParams) (db.User, error) {
+	args := r.Called(ctx, arg)
+	return args.Get(0).(db.User), args.Error(1)
+}
+
+func (r *FakeRepository) GetUser(ctx context.Context, id uuid.UUID) (db.User, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(db.User), args.Error(1)
+}
+
+func (r *FakeRepository) GetAllUsers(ctx context.Context) ([]db.User, error) {
+	args := r.Called(ctx)
+	return args.Get(0).([]db.User), args.Error(1)
+}
+
+func (r *FakeRepository) GetUserByNickname(ctx context.Context, nickname string) (db.User, error) {
+	args := r.Called(ctx, nickname)
+	return args.Get(0).(db.User), args.Error(1)
+}
+
+func (r *FakeRepository) CreateMessage(ctx context.Context, arg db.CreateMessageParams) (db.Message, error) {
+	args := r.Called(ctx, arg)
+	return args.Get(0).(db.Message), args.Error(1)
+}
+
+func (r *FakeRepository) GetRecentMessages(ctx context.Context, arg db.GetRecentMessagesParams) ([]db.Message, error) {
+	args := r.Called(ctx, arg)
+	return args.Get(0).([]db.Message), args.Error(1)
+}
+
+func (r *FakeRepository) GetMessagesBefore(ctx context.Context, arg db.GetMessagesBeforeParams) ([]db.Message, error) {
+	args := r.Called(ctx, arg)
+	return args.Get(0).([]db.Message), args.Error(1)
+}
+
+func (r *FakeRepository) GetMessage(ctx context.Context, id uuid.UUID) (db.Message, error) {
+	args := r.Called(ctx, id)
+	return args.Get(0).(db.Message), args.Error(1)
+}
+
+func (r *FakeRepository) EditMessage(ctx context.Context, arg db.EditMessageParams) (db.Message, error) {
+	args := r.Called(ctx, arg)
+	return args.Get(0).(db.Message), args.Error(1)
+}
+
+func (r *FakeRepository) DeleteMessage(ctx context.Context, arg db.DeleteMessageParams) (db.Message, error) {
+	args := r.Called(ctx, arg)
+	return args.Get(0).(db.Message), args.Error(1)
+}
+
+func (r *FakeRepository) ListMessageEdits(ctx context.Context, messageID uuid.UUID) ([]db.MessageEdit, error) {
+	args := r.Called(ctx, messageID)
+	return args.Get(0).([]db.MessageEdit), args.Error(1)
+}
+
+func (r *FakeRepository) AddReaction(ctx context.Context, arg db.AddReactionParams) (int64, error) {
+	args := r.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *FakeRepository) RemoveReaction(ctx context.Context, arg db.RemoveReactionParams) (int64, error) {
+	args := r.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *FakeRepository) CountReactions(ctx context.Context, arg db.CountReactionsParams) (int64, error) {
+	args := r.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *FakeRepository) ListReactionCounts(ctx context.Context, arg db.ListReactionCountsParams) ([]db.ListReactionCountsRow, error) {
+	args := r.Called(ctx, arg)
+	return args.Get(0).([]db.ListReactionCountsRow), args.Error(1)
+}
+
+func (r *FakeRepository) UpsertDMChannel(ctx context.Context, arg db.UpsertDMChannelParams) (db.DmChannel, error) {
+	args := r.Called(ctx, arg)
+	return args.Get(0).(db.DmChannel), args.Error(1)
+}
+
+func (r *FakeRepository) GetDMChannel(ctx context.Context, arg db.GetDMChannelParams) (db.DmChannel, error) {
+	args := r.Called(ctx, arg)
+	return args.Get(0).(db.DmChannel), args.Error(1)
+}
+
+func (r *FakeRepository) ListDMChannels(ctx context.Context, nickname string) ([]db.ListDMChannelsRow, error) {
+	args := r.Called(ctx, nickname)
+	return args.Get(0).([]db.ListDMChannelsRow), args.Error(1)
+}
+
+func (r *FakeRepository) CreateDirectMessage(ctx context.Context, arg db.CreateDirectMessageParams) (db.DirectMessage, error) {
+	args := r.Called(ctx, arg)
+	return args.Get(0).(db.DirectMessage), args.Error(1)
+}
+
+func (r *FakeRepository) GetRecentDirectMessages(ctx context.Context, arg db.GetRecentDirectMessagesParams) ([]db.DirectMessage, error) {
+	args := r.Called(ctx, arg)
+	return args.Get(0).([]db.DirectMessage), args.Error(1)
+}
+
+func (r *FakeRepository) GetDirectMessagesBefore(ctx context.Context, arg db.GetDirectMessagesBeforeParams) ([]db.DirectMessage, error) {
+	args := r.Called(ctx, arg)
+	return args.Get(0).([]db.DirectMessage), args.Error(1)
+}
+
+// FakeWSConn implementa a interface WSConn definida em ws
+type FakeWSConn struct {
+	readMessages [][]byte
+	readIndex    int
+	closed       bool
+}
+
+func (f *FakeWSConn) ReadMessage() (int, []byte, error) {
+	if f.readIndex >= len(f.readMessages) {
+		return 0, nil, fmt.Errorf("no message")
+	}
+	msg := f.readMessages[f.readIndex]
+	f.readIndex++
+	return websocket.TextMessage, msg, nil
+}
+
+func (f *FakeWSConn) SetReadLimit(limit int64)            {}
+func (f *FakeWSConn) SetReadDeadline(t time.Time) error   { return nil }
+func (f *FakeWSConn) SetWriteDeadline(t time.Time) error  { return nil }
+func (f *FakeWSConn) SetPongHandler(h func(string) error) {}
+func (f *FakeWSConn) Close() error {
+	f.closed = true
+	return nil
+}
+
+// Para os testes de ReadingPool não precisamos usar NextWriter e WriteMessage,
+// mas podemos implementar métodos mínimos se necessário:
+func (f *FakeWSConn) NextWriter(messageType int) (io.WriteCloser, error) {
+	return &FakeWriteCloser{}, nil
+}
+
+func (f *FakeWSConn) WriteMessage(messageType int, data []byte) error {
+	return nil
+}
+
+type FakeWriteCloser struct{}
+
+func (fwc *FakeWriteCloser) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+func (fwc *FakeWriteCloser) Close() error {
+	return nil
+}
+
+// FakeBus implementa a interface bus.Bus e sempre falha ao publicar
+type FakeBus struct{}
+
+func (f *FakeBus) Declare(topic string, kind bus.Kind) error { return nil }
+func (f *FakeBus) SetRetryPolicy(topic string, policy bus.RetryPolicy) error {
+	return nil
+}
+func (f *FakeBus) Publish(ctx context.Context, topic string, msg bus.Message) error {
+	return fmt.Errorf("fake bus error")
+}
+func (f *FakeBus) SetConcurrency(topic string, workers int) error    { return nil }
+func (f *FakeBus) Subscribe(topic string, handler bus.Handler) error { return nil }
+func (f *FakeBus) Status() bus.Status                                { return bus.Status{} }
+func (f *FakeBus) Shutdown(ctx context.Context) error                { return nil }
+func (f *FakeBus) Close() error                                      { return nil }
+
+// newWsService builds a WsService with its own message services, the way
+// config.NewApp shares them with the REST handlers.
+func newWsService(repo repository.RepositoryInterface, messageBus bus.Bus) *services.WsService {
+	return services.NewWsService(repo, services.NewMessageService(repo), services.NewDirectMessageService(repo), messageBus)
+}
+
+func newFakeHub() *ws.Hub {
+	return &ws.Hub{
+		Broadcast:  make(chan ws.Envelope, 10),
+		Register:   make(chan *ws.Client, 10),
+		Unregister: make(chan *ws.Client, 10),
+		Join:       make(chan ws.Subscription, 10),
+	}
+}
+
+func newFakeClient(hub *ws.Hub, messages ...string) *ws.Client {
+	fakeConn := &FakeWSConn{}
+	for _, msg := range messages {
+		fakeConn.readMessages = append(fakeConn.readMessages, []byte(msg))
+	}
+	return &ws.Client{
+		Hub:      hub,
+		Conn:     fakeConn,
+		Send:     make(chan []byte, 10),
+		Nickname: "TestUser",
+		Room:     ws.DefaultRoom,
+	}
+}
+
+// runReadingPool lê todas as mensagens do cliente e espera o ReadingPool terminar
+func runReadingPool(svc *services.WsService, client *ws.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	go svc.ReadingPool(ctx, client)
+	time.Sleep(200 * time.Millisecond)
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+	"github.com/LuccChagas/my-chat-app/internal/models"
+	"github.com/LuccChagas/my-chat-app/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type MessageService struct {
+	repository repository.RepositoryInterface
+	moderators map[string]bool
+}
+
+func NewMessageService(repository repository.RepositoryInterface) *MessageService {
+	return &MessageService{
+		repository: repository,
+	}
+}
+
+// GetMessages returns one page of a room history, oldest message first, with
+// the reactions of each message as seen by viewer.
+// An empty cursor starts from the newest message; NextCursor points to the
+// page right before the returned one and is empty when there is nothing older.
+func (s *MessageService) GetMessages(ctx context.Context, room, viewer, cursor string, limit int) (models.MessagePage, error) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	// one extra row tells whether an older page exists
+	var (
+		messages []db.Message
+		err      error
+	)
+	if cursor == "" {
+		messages, err = s.repository.GetRecentMessages(ctx, db.GetRecentMessagesParams{
+			Room:  room,
+			Limit: int32(limit + 1),
+		})
+	} else {
+		createdAt, id, decodeErr := DecodeCursor(cursor)
+		if decodeErr != nil {
+			return models.MessagePage{}, decodeErr
+		}
+		messages, err = s.repository.GetMessagesBefore(ctx, db.GetMessagesBeforeParams{
+			Room:      room,
+			CreatedAt: createdAt,
+			ID:        id,
+			RowLimit:  int32(limit + 1),
+		})
+	}
+	if err != nil {
+		return models.MessagePage{}, err
+	}
+
+	page := models.MessagePage{}
+	if len(messages) > limit {
+		messages = messages[:limit]
+		oldest := messages[limit-1]
+		page.NextCursor = EncodeCursor(oldest.CreatedAt, oldest.ID)
+	}
+
+	page.Messages = make([]models.Message, len(messages))
+	for i, message := range messages {
+		page.Messages[len(messages)-1-i] = toMessageModel(message)
+	}
+	if err := s.attachReactions(ctx, page.Messages, viewer); err != nil {
+		return models.MessagePage{}, err
+	}
+
+	return page, nil
+}
+
+// EncodeCursor builds the opaque pagination cursor for a (created_at, id) key.
+func EncodeCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	createdAtStr, idStr, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+
+	return createdAt, id, nil
+}
+
+func toMessageModel(message db.Message) models.Message {
+	m := models.Message{
+		ID:        message.ID,
+		Room:      message.Room,
+		Timestamp: message.CreatedAt,
+		Content:   message.Content,
+		Author:    message.Author,
+		Deleted:   message.DeletedAt.Valid,
+	}
+	if message.EditedAt.Valid {
+		m.EditedAt = &message.EditedAt.Time
+	}
+	return m
+}
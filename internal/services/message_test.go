@@ -0,0 +1,127 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+	"github.com/LuccChagas/my-chat-app/internal/models"
+	"github.com/LuccChagas/my-chat-app/internal/services"
+)
+
+func newStoredMessages(room string, n int, newest time.Time) []db.Message {
+	messages := make([]db.Message, n)
+	for i := range messages {
+		messages[i] = db.Message{
+			ID:        uuid.New(),
+			Room:      room,
+			Author:    "author",
+			Content:   "message",
+			CreatedAt: newest.Add(-time.Duration(i) * time.Second),
+		}
+	}
+	return messages
+}
+
+func TestGetMessages_FirstPage(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewMessageService(fakeRepo)
+	stored := newStoredMessages("general", 3, time.Now())
+
+	fakeRepo.
+		On("GetRecentMessages", mock.Anything, db.GetRecentMessagesParams{Room: "general", Limit: 3}).
+		Return(stored, nil)
+	fakeRepo.
+		On("ListReactionCounts", mock.Anything, mock.Anything).
+		Return([]db.ListReactionCountsRow{}, nil)
+
+	page, err := svc.GetMessages(context.Background(), "general", "viewer", "", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(page.Messages))
+	// oldest first
+	assert.Equal(t, stored[1].ID, page.Messages[0].ID)
+	assert.Equal(t, stored[0].ID, page.Messages[1].ID)
+	assert.Equal(t, services.EncodeCursor(stored[1].CreatedAt, stored[1].ID), page.NextCursor)
+}
+
+func TestGetMessages_WithCursor(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewMessageService(fakeRepo)
+	now := time.Now()
+	cursorID := uuid.New()
+	stored := newStoredMessages("general", 1, now.Add(-time.Minute))
+
+	fakeRepo.
+		On("GetMessagesBefore", mock.Anything, mock.Anything).
+		Return(stored, nil).
+		Run(func(args mock.Arguments) {
+			arg := args.Get(1).(db.GetMessagesBeforeParams)
+			assert.Equal(t, "general", arg.Room)
+			assert.Equal(t, cursorID, arg.ID)
+			assert.True(t, now.Equal(arg.CreatedAt))
+			assert.Equal(t, int32(services.DefaultPageSize+1), arg.RowLimit)
+		})
+	fakeRepo.
+		On("ListReactionCounts", mock.Anything, mock.Anything).
+		Return([]db.ListReactionCountsRow{}, nil)
+
+	page, err := svc.GetMessages(context.Background(), "general", "viewer", services.EncodeCursor(now, cursorID), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(page.Messages))
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestGetMessages_LimitIsCapped(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewMessageService(fakeRepo)
+
+	fakeRepo.
+		On("GetRecentMessages", mock.Anything, db.GetRecentMessagesParams{Room: "general", Limit: services.MaxPageSize + 1}).
+		Return([]db.Message{}, nil)
+
+	page, err := svc.GetMessages(context.Background(), "general", "viewer", "", 1000)
+	assert.NoError(t, err)
+	assert.Empty(t, page.Messages)
+	fakeRepo.AssertExpectations(t)
+}
+
+func TestGetMessages_InvalidCursor(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewMessageService(fakeRepo)
+
+	_, err := svc.GetMessages(context.Background(), "general", "viewer", "not-a-cursor", 10)
+	assert.ErrorIs(t, err, services.ErrInvalidCursor)
+	fakeRepo.AssertNotCalled(t, "GetMessagesBefore", mock.Anything, mock.Anything)
+}
+
+func TestGetMessages_Reactions(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewMessageService(fakeRepo)
+	stored := newStoredMessages("general", 2, time.Now())
+
+	fakeRepo.
+		On("GetRecentMessages", mock.Anything, db.GetRecentMessagesParams{Room: "general", Limit: 3}).
+		Return(stored, nil)
+	fakeRepo.
+		On("ListReactionCounts", mock.Anything, db.ListReactionCountsParams{
+			Nickname:   "viewer",
+			MessageIds: []uuid.UUID{stored[1].ID, stored[0].ID},
+		}).
+		Return([]db.ListReactionCountsRow{
+			{MessageID: stored[0].ID, Emoji: "👍", Count: 3, Reacted: true},
+			{MessageID: stored[0].ID, Emoji: "🎉", Count: 1},
+		}, nil)
+
+	page, err := svc.GetMessages(context.Background(), "general", "viewer", "", 2)
+	assert.NoError(t, err)
+	assert.Empty(t, page.Messages[0].Reactions)
+	assert.Equal(t, []models.ReactionCount{
+		{Emoji: "👍", Count: 3, Reacted: true},
+		{Emoji: "🎉", Count: 1},
+	}, page.Messages[1].Reactions)
+}
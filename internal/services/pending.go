@@ -0,0 +1,52 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// pendingReplies tracks the bot commands that have not been answered yet.
+type pendingReplies struct {
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+}
+
+func newPendingReplies() *pendingReplies {
+	return &pendingReplies{timers: make(map[string]*time.Timer)}
+}
+
+// add calls onTimeout unless id is resolved within timeout.
+func (p *pendingReplies) add(id string, timeout time.Duration, onTimeout func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.timers[id] = time.AfterFunc(timeout, func() {
+		if p.resolve(id) {
+			onTimeout()
+		}
+	})
+}
+
+// extend restarts the timer of id, if still pending, to fire after timeout.
+func (p *pendingReplies) extend(id string, timeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if timer, ok := p.timers[id]; ok {
+		timer.Reset(timeout)
+	}
+}
+
+// resolve forgets id and reports whether it was still pending.
+func (p *pendingReplies) resolve(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	timer, ok := p.timers[id]
+	if !ok {
+		return false
+	}
+	timer.Stop()
+	delete(p.timers, id)
+	return true
+}
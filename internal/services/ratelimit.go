@@ -0,0 +1,114 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket: Burst commands can be sent at once, and one
+// more every Interval afterwards. A zero Burst or Interval disables it.
+type RateLimit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+func (r RateLimit) enabled() bool {
+	return r.Burst > 0 && r.Interval > 0
+}
+
+var (
+	// DefaultUserRateLimit lets a user send 5 bot commands at once, then one
+	// every 3 seconds.
+	DefaultUserRateLimit = RateLimit{Burst: 5, Interval: 3 * time.Second}
+	// DefaultRoomRateLimit lets a room send 20 bot commands at once, then
+	// one per second.
+	DefaultRoomRateLimit = RateLimit{Burst: 20, Interval: time.Second}
+)
+
+// idleBucketSweep is how often the buckets back to full are forgotten.
+const idleBucketSweep = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimiter keeps a token bucket per key, created full on first use.
+type rateLimiter struct {
+	limit RateLimit
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	return &rateLimiter{
+		limit:   limit,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// take removes a token from the bucket of key. When none is left it returns
+// false and how long until the next one.
+func (l *rateLimiter) take(key string, now time.Time) (bool, time.Duration) {
+	if !l.limit.enabled() {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	b := l.refill(key, now)
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(l.limit.Interval))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// giveBack returns the token taken for a command that was not sent.
+func (l *rateLimiter) giveBack(key string, now time.Time) {
+	if !l.limit.enabled() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(key, now)
+	b.tokens = min(b.tokens+1, float64(l.limit.Burst))
+}
+
+func (l *rateLimiter) refill(key string, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit.Burst), updated: now}
+		l.buckets[key] = b
+		return b
+	}
+
+	b.tokens = min(b.tokens+l.earned(b, now), float64(l.limit.Burst))
+	b.updated = now
+	return b
+}
+
+// sweep drops the buckets that are full again, which behave like new ones.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < idleBucketSweep {
+		return
+	}
+	l.swept = now
+
+	for key, b := range l.buckets {
+		if b.tokens+l.earned(b, now) >= float64(l.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// earned is how many tokens b got back since it was last updated.
+func (l *rateLimiter) earned(b *bucket, now time.Time) float64 {
+	return float64(now.Sub(b.updated)) / float64(l.limit.Interval)
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+	"github.com/LuccChagas/my-chat-app/internal/models"
+	"github.com/google/uuid"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxEmojiSize is enough for the longest emoji sequences, such as families
+// joined with zero-width joiners.
+const maxEmojiSize = 32
+
+var ErrInvalidEmoji = errors.New("invalid emoji")
+
+// React adds the emoji reaction of nickname to a message, or removes it. A
+// user reacts at most once with each emoji, so changed is false when there
+// was nothing to do.
+func (s *MessageService) React(ctx context.Context, id uuid.UUID, nickname, emoji string, remove bool) (reaction models.Reaction, changed bool, err error) {
+	if !validEmoji(emoji) {
+		return models.Reaction{}, false, ErrInvalidEmoji
+	}
+
+	message, err := s.repository.GetMessage(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Reaction{}, false, ErrMessageNotFound
+	}
+	if err != nil {
+		return models.Reaction{}, false, err
+	}
+	if message.DeletedAt.Valid && !remove {
+		return models.Reaction{}, false, ErrMessageDeleted
+	}
+
+	var rows int64
+	if remove {
+		rows, err = s.repository.RemoveReaction(ctx, db.RemoveReactionParams{MessageID: id, Nickname: nickname, Emoji: emoji})
+	} else {
+		rows, err = s.repository.AddReaction(ctx, db.AddReactionParams{MessageID: id, Nickname: nickname, Emoji: emoji})
+	}
+	if err != nil {
+		return models.Reaction{}, false, err
+	}
+
+	count, err := s.repository.CountReactions(ctx, db.CountReactionsParams{MessageID: id, Emoji: emoji})
+	if err != nil {
+		return models.Reaction{}, false, err
+	}
+
+	return models.Reaction{
+		MessageID: id,
+		Room:      message.Room,
+		Nickname:  nickname,
+		Emoji:     emoji,
+		Removed:   remove,
+		Count:     int(count),
+	}, rows > 0, nil
+}
+
+// attachReactions fills the reaction counts of messages, as seen by viewer.
+func (s *MessageService) attachReactions(ctx context.Context, messages []models.Message, viewer string) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(messages))
+	byID := make(map[uuid.UUID]*models.Message, len(messages))
+	for i := range messages {
+		ids[i] = messages[i].ID
+		byID[messages[i].ID] = &messages[i]
+	}
+
+	counts, err := s.repository.ListReactionCounts(ctx, db.ListReactionCountsParams{
+		Nickname:   viewer,
+		MessageIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, count := range counts {
+		if message, ok := byID[count.MessageID]; ok {
+			message.Reactions = append(message.Reactions, models.ReactionCount{
+				Emoji:   count.Emoji,
+				Count:   int(count.Count),
+				Reacted: count.Reacted,
+			})
+		}
+	}
+	return nil
+}
+
+// validEmoji accepts a short printable sequence holding at least one symbol,
+// which lets through emoji with skin tones and joiners but not words.
+func validEmoji(emoji string) bool {
+	if emoji == "" || len(emoji) > maxEmojiSize || !utf8.ValidString(emoji) {
+		return false
+	}
+
+	symbol := false
+	for _, r := range emoji {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || unicode.IsLetter(r) {
+			return false
+		}
+		if unicode.IsSymbol(r) {
+			symbol = true
+		}
+	}
+	return symbol
+}
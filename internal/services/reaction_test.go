@@ -0,0 +1,95 @@
+package services_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+	"github.com/LuccChagas/my-chat-app/internal/services"
+)
+
+func TestReact_Add(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewMessageService(fakeRepo)
+	stored := db.Message{ID: uuid.New(), Room: "random", Author: "alice", Content: "hi", CreatedAt: time.Now()}
+
+	fakeRepo.On("GetMessage", mock.Anything, stored.ID).Return(stored, nil)
+	fakeRepo.
+		On("AddReaction", mock.Anything, db.AddReactionParams{MessageID: stored.ID, Nickname: "bob", Emoji: "👍"}).
+		Return(int64(1), nil)
+	fakeRepo.
+		On("CountReactions", mock.Anything, db.CountReactionsParams{MessageID: stored.ID, Emoji: "👍"}).
+		Return(int64(2), nil)
+
+	reaction, changed, err := svc.React(context.Background(), stored.ID, "bob", "👍", false)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "random", reaction.Room)
+	assert.Equal(t, "bob", reaction.Nickname)
+	assert.Equal(t, 2, reaction.Count)
+	assert.False(t, reaction.Removed)
+}
+
+func TestReact_AlreadyThere(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewMessageService(fakeRepo)
+	stored := db.Message{ID: uuid.New(), Room: "general", Author: "alice", Content: "hi", CreatedAt: time.Now()}
+
+	fakeRepo.On("GetMessage", mock.Anything, stored.ID).Return(stored, nil)
+	fakeRepo.On("AddReaction", mock.Anything, mock.Anything).Return(int64(0), nil)
+	fakeRepo.On("CountReactions", mock.Anything, mock.Anything).Return(int64(1), nil)
+
+	reaction, changed, err := svc.React(context.Background(), stored.ID, "bob", "👍", false)
+	assert.NoError(t, err)
+	assert.False(t, changed, "A user reacts once per emoji")
+	assert.Equal(t, 1, reaction.Count)
+}
+
+func TestReact_Remove(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewMessageService(fakeRepo)
+	stored := db.Message{ID: uuid.New(), Room: "general", Author: "alice", Content: "hi", CreatedAt: time.Now()}
+
+	fakeRepo.On("GetMessage", mock.Anything, stored.ID).Return(stored, nil)
+	fakeRepo.
+		On("RemoveReaction", mock.Anything, db.RemoveReactionParams{MessageID: stored.ID, Nickname: "bob", Emoji: "👍"}).
+		Return(int64(1), nil)
+	fakeRepo.On("CountReactions", mock.Anything, mock.Anything).Return(int64(0), nil)
+
+	reaction, changed, err := svc.React(context.Background(), stored.ID, "bob", "👍", true)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, reaction.Removed)
+	assert.Zero(t, reaction.Count)
+	fakeRepo.AssertNotCalled(t, "AddReaction", mock.Anything, mock.Anything)
+}
+
+func TestReact_Rejected(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := services.NewMessageService(fakeRepo)
+	unknown := uuid.New()
+	deleted := db.Message{
+		ID: uuid.New(), Room: "general", Author: "alice", CreatedAt: time.Now(),
+		DeletedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+
+	fakeRepo.On("GetMessage", mock.Anything, unknown).Return(db.Message{}, sql.ErrNoRows)
+	fakeRepo.On("GetMessage", mock.Anything, deleted.ID).Return(deleted, nil)
+
+	for _, emoji := range []string{"", "lol", "👍 👍", strings.Repeat("👍", 9)} {
+		_, _, err := svc.React(context.Background(), deleted.ID, "bob", emoji, false)
+		assert.ErrorIs(t, err, services.ErrInvalidEmoji, "emoji %q", emoji)
+	}
+	_, _, err := svc.React(context.Background(), unknown, "bob", "👍", false)
+	assert.ErrorIs(t, err, services.ErrMessageNotFound)
+	_, _, err = svc.React(context.Background(), deleted.ID, "bob", "👍", false)
+	assert.ErrorIs(t, err, services.ErrMessageDeleted)
+	fakeRepo.AssertNotCalled(t, "AddReaction", mock.Anything, mock.Anything)
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"github.com/LuccChagas/my-chat-app/internal/bot"
 	"github.com/LuccChagas/my-chat-app/internal/models"
 	"github.com/LuccChagas/my-chat-app/internal/websocket"
 	socket "github.com/LuccChagas/my-chat-app/internal/websocket"
@@ -14,9 +15,17 @@ type UserServiceInterface interface {
 	GetUserByUsername(ctx context.Context, username string) (models.UserResponse, error)
 }
 
+type MessageServiceInterface interface {
+	GetMessages(ctx context.Context, room, viewer, cursor string, limit int) (models.MessagePage, error)
+}
+
 type WsServiceInterface interface {
 	ReadingPool(ctx context.Context, client *websocket.Client)
 	WritingPool(ctx context.Context, client *websocket.Client)
-	PublishStockRequest(stock string)
-	GetStockResponse(hub *socket.Hub) error
+	SaveMessage(ctx context.Context, room, author, content string) (models.Message, error)
+	RecentMessages(ctx context.Context, room string) ([]models.Message, error)
+	SendHistory(ctx context.Context, client *websocket.Client)
+	PublishBotRequest(ctx context.Context, req bot.Request) error
+	ConsumeBotReplies(hub *socket.Hub) error
+	ObserveBroadcast(env socket.Envelope)
 }
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"encoding/json"
+	ws "github.com/LuccChagas/my-chat-app/internal/websocket"
+	"time"
+)
+
+const (
+	// typingThrottle is the shortest time between two typing envelopes a
+	// client may fan out; the ones in between are dropped.
+	typingThrottle = 2 * time.Second
+	// typingTTL is how long receivers show a typing indicator that is not
+	// refreshed. It is longer than typingThrottle so a client that keeps
+	// typing never blinks.
+	typingTTL = 5 * time.Second
+)
+
+// typingState throttles the typing envelopes of one client. It is owned by
+// the client's reading goroutine.
+type typingState struct {
+	active bool
+	sent   time.Time
+}
+
+// allow reports whether a typing envelope must be fanned out. A start is
+// only sent once per typingThrottle and a stop only after a start.
+func (t *typingState) allow(active bool, now time.Time) bool {
+	if !active {
+		if !t.active {
+			return false
+		}
+		t.reset()
+		return true
+	}
+
+	if t.active && now.Sub(t.sent) < typingThrottle {
+		return false
+	}
+	t.active, t.sent = true, now
+	return true
+}
+
+// reset forgets the last start, as sending a message ends the typing.
+func (t *typingState) reset() {
+	*t = typingState{}
+}
+
+// handleTyping fans a typing envelope out to the other members of the
+// client's room. It bypasses the chat checks since it carries no text.
+func handleTyping(client *ws.Client, env ws.Envelope, typing *typingState, now time.Time) {
+	var payload ws.TypingPayload
+	if len(env.Payload) > 0 {
+		if err := json.Unmarshal(env.Payload, &payload); err != nil {
+			client.SendEnvelope(ws.ErrorEnvelope(client.Room, "bad_request", "Invalid typing payload"))
+			return
+		}
+	}
+	if !typing.allow(payload.Active, now) {
+		return
+	}
+
+	if payload.Active {
+		payload.TTL = int(typingTTL / time.Millisecond)
+	}
+	client.Hub.Broadcast <- ws.NewEnvelope(ws.TypeTyping, client.Room, client.Nickname, payload)
+}
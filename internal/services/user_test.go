@@ -15,30 +15,6 @@ import (
 	"github.com/LuccChagas/my-chat-app/internal/services"
 )
 
-type FakeRepository struct {
-	mock.Mock
-}
-
-func (r *FakeRepository) CreateUser(ctx context.Context, arg db.CreateUsersParams) (db.User, error) {
-	args := r.Called(ctx, arg)
-	return args.Get(0).(db.User), args.Error(1)
-}
-
-func (r *FakeRepository) GetUser(ctx context.Context, id uuid.UUID) (db.User, error) {
-	args := r.Called(ctx, id)
-	return args.Get(0).(db.User), args.Error(1)
-}
-
-func (r *FakeRepository) GetAllUsers(ctx context.Context) ([]db.User, error) {
-	args := r.Called(ctx)
-	return args.Get(0).([]db.User), args.Error(1)
-}
-
-func (r *FakeRepository) GetUserByNickname(ctx context.Context, nickname string) (db.User, error) {
-	args := r.Called(ctx, nickname)
-	return args.Get(0).(db.User), args.Error(1)
-}
-
 func TestCreateUser_Success(t *testing.T) {
 	// Para este teste, não sobrescrevemos as funções de hash.
 	fakeRepo := new(FakeRepository)
@@ -3,36 +3,72 @@ package services
 import "C"
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+	"github.com/LuccChagas/my-chat-app/internal/bot"
+	"github.com/LuccChagas/my-chat-app/internal/commands"
 	"github.com/LuccChagas/my-chat-app/internal/models"
 	"github.com/LuccChagas/my-chat-app/internal/repository"
 	ws "github.com/LuccChagas/my-chat-app/internal/websocket"
-	"github.com/LuccChagas/my-chat-app/pkg"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/labstack/gommon/log"
-	"github.com/rabbitmq/amqp091-go"
 	"strings"
 	"time"
 )
 
 const (
-	pongWait   = 60 * time.Second
-	writeWait  = 10 * time.Second
-	pingPeriod = (pongWait * 9) / 10
+	pongWait     = 60 * time.Second
+	writeWait    = 10 * time.Second
+	pingPeriod   = (pongWait * 9) / 10
+	historyLimit = 50
+
+	// maxMessageSize limits the chat text, maxFrameSize the whole envelope.
+	maxMessageSize = 280
+	maxFrameSize   = 2048
+
+	stockBotName = "StockBot"
+
+	// DefaultBotReplyTimeout is how long a user waits for the bot before
+	// being told that it did not answer. It outlasts one call to the quote
+	// service; a retry notice from the bot extends it.
+	DefaultBotReplyTimeout = 15 * time.Second
 )
 
 var newline = []byte{'\n'}
 
 type WsService struct {
-	repository repository.RepositoryInterface
-	rabbit     pkg.RabbitMQConnection
+	repository   repository.RepositoryInterface
+	direct       *DirectMessageService
+	messages     *MessageService
+	bus          bus.Bus
+	commands     *commands.Registry
+	pending      *pendingReplies
+	replyTimeout time.Duration
+	admins       map[string]bool
+	userLimits   *rateLimiter
+	roomLimits   *rateLimiter
 }
 
-func NewWsService(repository repository.RepositoryInterface, rabbit pkg.RabbitMQConnection) *WsService {
-	return &WsService{
-		repository: repository,
-		rabbit:     rabbit,
+// NewWsService builds the websocket service on top of the message services
+// used by the REST handlers, so both apply the same rules.
+func NewWsService(repository repository.RepositoryInterface, messages *MessageService, direct *DirectMessageService, messageBus bus.Bus) *WsService {
+	s := &WsService{
+		repository:   repository,
+		direct:       direct,
+		messages:     messages,
+		bus:          messageBus,
+		commands:     commands.NewRegistry(),
+		pending:      newPendingReplies(),
+		replyTimeout: DefaultBotReplyTimeout,
+		userLimits:   newRateLimiter(DefaultUserRateLimit),
+		roomLimits:   newRateLimiter(DefaultRoomRateLimit),
 	}
+	s.registerCommands()
+	return s
 }
 
 func (s *WsService) ReadingPool(ctx context.Context, client *ws.Client) {
@@ -41,13 +77,14 @@ func (s *WsService) ReadingPool(ctx context.Context, client *ws.Client) {
 		client.Conn.Close()
 	}()
 
-	client.Conn.SetReadLimit(280)
+	client.Conn.SetReadLimit(maxFrameSize)
 	client.Conn.SetReadDeadline(time.Now().Add(pongWait))
 	client.Conn.SetPongHandler(func(string) error {
 		client.Conn.SetReadDeadline(time.Now().Add(pongWait))
 		return nil
 	})
 
+	var typing typingState
 	for {
 		select {
 		case <-ctx.Done():
@@ -62,40 +99,304 @@ func (s *WsService) ReadingPool(ctx context.Context, client *ws.Client) {
 			}
 			break
 		}
+		client.Touch()
 
-		msgStr := string(message)
+		env, err := ws.DecodeEnvelope(message, client.Format)
+		if err != nil {
+			client.SendEnvelope(ws.ErrorEnvelope(client.Room, "bad_request", err.Error()))
+			continue
+		}
+
+		switch env.Type {
+		case ws.TypeChat:
+			typing.reset()
+			s.handleChat(ctx, client, env)
+		case ws.TypeTyping:
+			handleTyping(client, env, &typing, time.Now())
+		case ws.TypeEdit, ws.TypeDelete:
+			s.handleChange(ctx, client, env)
+		case ws.TypeReaction:
+			s.handleReaction(ctx, client, env)
+		case ws.TypeDirect:
+			s.handleDirect(ctx, client, env)
+		default:
+			client.SendEnvelope(ws.ErrorEnvelope(client.Room, "unsupported_type",
+				fmt.Sprintf("Unsupported message type: %s", env.Type)))
+		}
+	}
+}
 
-		if strings.HasPrefix(msgStr, "/stock=") {
-			stockCode := strings.TrimPrefix(msgStr, "/stock=")
+func (s *WsService) handleChat(ctx context.Context, client *ws.Client, env ws.Envelope) {
+	msgStr := env.Text()
 
-			err := s.PublishStockRequest(stockCode)
-			if err != nil {
-				log.Printf("Error publishing stock: %v", err)
-			}
+	if env.Room != "" && env.Room != client.Room {
+		client.SendEnvelope(ws.ErrorEnvelope(client.Room, "wrong_room",
+			fmt.Sprintf("You are not in room %s, use /join first", env.Room)))
+		return
+	}
+	if !checkText(client, msgStr) {
+		return
+	}
 
-			confirmationMsg := fmt.Sprintf("Processing command for stock code: %s", stockCode)
-			timestamp := time.Now().Format("15:04:05")
-			client.Hub.Broadcast <- []byte(fmt.Sprintf("[%s] %s", timestamp, confirmationMsg))
-			continue
+	if strings.HasPrefix(msgStr, "/") {
+		// the invocation id is acked to the client and comes back in bot replies
+		id := uuid.NewString()
+		s.ack(client, env.ID, id)
+		if err := s.commands.Dispatch(ctx, id, client, msgStr); err != nil {
+			client.SendEnvelope(ws.ErrorEnvelope(client.Room, "command_error", err.Error()))
 		}
+		return
+	}
 
-		timestamp := time.Now().Format("15:04:05")
-		formattedMsg := fmt.Sprintf("[%s] %s: %s", timestamp, client.Nickname, msgStr)
+	newMsg, err := s.SaveMessage(ctx, client.Room, client.Nickname, msgStr)
+	if err != nil {
+		// an unsaved message could not be edited or reacted to later
+		log.Printf("Error saving message: %v", err)
+		client.SendEnvelope(ws.ErrorEnvelope(client.Room, "save_failed", "Your message could not be sent, try again later"))
+		return
+	}
 
-		newMsg := models.Message{
-			Timestamp: time.Now(),
-			Content:   msgStr,
-			Author:    client.Nickname,
-		}
-		client.Hub.Messages = append(client.Hub.Messages, newMsg)
-		if len(client.Hub.Messages) > 50 {
-			client.Hub.Messages = client.Hub.Messages[len(client.Hub.Messages)-50:]
-		}
+	s.ack(client, env.ID, newMsg.ID.String())
+	client.Hub.Broadcast <- chatEnvelope(newMsg)
+}
+
+// checkText tells the client why a message text cannot be sent, if it can't.
+func checkText(client *ws.Client, text string) bool {
+	if strings.TrimSpace(text) == "" {
+		client.SendEnvelope(ws.ErrorEnvelope(client.Room, "empty_message", "Message is empty"))
+		return false
+	}
+	if len(text) > maxMessageSize {
+		client.SendEnvelope(ws.ErrorEnvelope(client.Room, "message_too_long",
+			fmt.Sprintf("Messages are limited to %d bytes", maxMessageSize)))
+		return false
+	}
+	return true
+}
+
+// handleChange edits or deletes the message named in the payload ref and
+// sends the update to its room.
+func (s *WsService) handleChange(ctx context.Context, client *ws.Client, env ws.Envelope) {
+	var payload ws.EditPayload
+	_ = json.Unmarshal(env.Payload, &payload)
+	id, err := uuid.Parse(payload.Ref)
+	if err != nil {
+		client.SendEnvelope(ws.ErrorEnvelope(client.Room, "bad_request", "Invalid message id"))
+		return
+	}
+
+	var message models.Message
+	if env.Type == ws.TypeEdit {
+		message, err = s.messages.EditMessage(ctx, id, client.Nickname, payload.Text)
+	} else {
+		message, err = s.messages.DeleteMessage(ctx, id, client.Nickname)
+	}
+	switch {
+	case errors.Is(err, ErrMessageNotFound), errors.Is(err, ErrMessageDeleted), errors.Is(err, ErrNotAllowed),
+		errors.Is(err, ErrEmptyMessage), errors.Is(err, ErrMessageTooLong):
+		client.SendEnvelope(ws.ErrorEnvelope(client.Room, env.Type+"_error", err.Error()))
+		return
+	case err != nil:
+		log.Printf("Error changing message %s: %v", id, err)
+		client.SendEnvelope(ws.ErrorEnvelope(client.Room, env.Type+"_error", "Could not change the message, try again later"))
+		return
+	}
+
+	s.ack(client, env.ID, message.ID.String())
+	client.Hub.Broadcast <- UpdateEnvelope(message)
+}
+
+// handleReaction adds or removes a reaction of the client's user and sends
+// the new count to the message room when it changed.
+func (s *WsService) handleReaction(ctx context.Context, client *ws.Client, env ws.Envelope) {
+	var payload ws.ReactionPayload
+	_ = json.Unmarshal(env.Payload, &payload)
+	id, err := uuid.Parse(payload.Ref)
+	if err != nil {
+		client.SendEnvelope(ws.ErrorEnvelope(client.Room, "bad_request", "Invalid message id"))
+		return
+	}
+
+	reaction, changed, err := s.messages.React(ctx, id, client.Nickname, payload.Emoji, payload.Remove)
+	switch {
+	case errors.Is(err, ErrInvalidEmoji), errors.Is(err, ErrMessageNotFound), errors.Is(err, ErrMessageDeleted):
+		client.SendEnvelope(ws.ErrorEnvelope(client.Room, "reaction_error", err.Error()))
+		return
+	case err != nil:
+		log.Printf("Error reacting to message %s: %v", id, err)
+		client.SendEnvelope(ws.ErrorEnvelope(client.Room, "reaction_error", "Could not save the reaction, try again later"))
+		return
+	}
+
+	s.ack(client, env.ID, id.String())
+	if changed {
+		client.Hub.Broadcast <- ReactionEnvelope(reaction)
+	}
+}
+
+// handleDirect sends a direct message envelope to the user named in To.
+func (s *WsService) handleDirect(ctx context.Context, client *ws.Client, env ws.Envelope) {
+	if len(env.To) != 1 {
+		client.SendEnvelope(ws.ErrorEnvelope(client.Room, "invalid_recipient",
+			"Direct messages have exactly one recipient"))
+		return
+	}
+	text := env.Text()
+	if !checkText(client, text) {
+		return
+	}
 
-		client.Hub.Broadcast <- []byte(formattedMsg)
+	if err := s.sendDirect(ctx, client, env.ID, env.To[0], text); err != nil {
+		client.SendEnvelope(ws.ErrorEnvelope(client.Room, "dm_error", err.Error()))
 	}
 }
 
+// sendDirect stores a direct message and delivers it to every connection
+// of both participants.
+func (s *WsService) sendDirect(ctx context.Context, client *ws.Client, ref, to, text string) error {
+	message, err := s.direct.SendDirectMessage(ctx, client.Nickname, to, text)
+	switch {
+	case errors.Is(err, ErrUnknownUser):
+		return fmt.Errorf("user %s does not exist", to)
+	case errors.Is(err, ErrSelfMessage), errors.Is(err, ErrEmptyNickname):
+		return err
+	case err != nil:
+		log.Printf("Error saving direct message: %v", err)
+		return fmt.Errorf("could not send the message to %s, try again later", to)
+	}
+
+	s.ack(client, ref, message.ID.String())
+	client.Hub.Broadcast <- directEnvelope(message)
+	return nil
+}
+
+func directEnvelope(message models.DirectMessage) ws.Envelope {
+	env := ws.NewEnvelope(ws.TypeDirect, "", message.Author, ws.DirectPayload{
+		Text:    message.Content,
+		Channel: message.Channel.String(),
+	})
+	env.ID = message.ID.String()
+	env.To = []string{message.Author, message.To}
+	env.Timestamp = message.Timestamp
+	return env
+}
+
+// ack confirms a client envelope; id is the id the server assigned to it.
+func (s *WsService) ack(client *ws.Client, ref, id string) {
+	if ref == "" {
+		return
+	}
+
+	env := ws.NewEnvelope(ws.TypeAck, client.Room, "", ws.AckPayload{Ref: ref})
+	env.ID = id
+	client.SendEnvelope(env)
+}
+
+func chatEnvelope(msg models.Message) ws.Envelope {
+	env := ws.NewEnvelope(ws.TypeChat, msg.Room, msg.Author, messagePayload(msg))
+	env.ID = msg.ID.String()
+	env.Timestamp = msg.Timestamp
+	return env
+}
+
+// UpdateEnvelope tells the members of the message room that it was edited
+// or deleted. Its id is the message id, so clients replace the message.
+func UpdateEnvelope(msg models.Message) ws.Envelope {
+	env := ws.NewEnvelope(ws.TypeUpdate, msg.Room, msg.Author, messagePayload(msg))
+	env.ID = msg.ID.String()
+	return env
+}
+
+func messagePayload(msg models.Message) ws.TextPayload {
+	payload := ws.TextPayload{
+		Text:     msg.Content,
+		EditedAt: msg.EditedAt,
+		Deleted:  msg.Deleted,
+	}
+	if len(msg.Reactions) > 0 {
+		payload.Reactions, _ = json.Marshal(msg.Reactions)
+	}
+	return payload
+}
+
+// ReactionEnvelope tells the members of the message room that a reaction
+// was added or removed.
+func ReactionEnvelope(reaction models.Reaction) ws.Envelope {
+	return ws.NewEnvelope(ws.TypeReaction, reaction.Room, reaction.Nickname, ws.ReactionPayload{
+		Ref:    reaction.MessageID.String(),
+		Emoji:  reaction.Emoji,
+		Remove: reaction.Removed,
+		Count:  reaction.Count,
+	})
+}
+
+// SaveMessage stores a chat message.
+func (s *WsService) SaveMessage(ctx context.Context, room, author, content string) (models.Message, error) {
+	arg := db.CreateMessageParams{
+		ID:      uuid.New(),
+		Room:    room,
+		Author:  author,
+		Content: content,
+	}
+
+	message, err := s.repository.CreateMessage(ctx, arg)
+	if err != nil {
+		return models.Message{}, err
+	}
+
+	return toMessageModel(message), nil
+}
+
+// RecentMessages returns the latest messages of a room in chronological order.
+func (s *WsService) RecentMessages(ctx context.Context, room string) ([]models.Message, error) {
+	messages, err := s.repository.GetRecentMessages(ctx, db.GetRecentMessagesParams{
+		Room:  room,
+		Limit: historyLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]models.Message, len(messages))
+	for i, message := range messages {
+		response[len(messages)-1-i] = toMessageModel(message)
+	}
+
+	return response, nil
+}
+
+// SendHistory replays the recent messages of the client's room to the client only.
+func (s *WsService) SendHistory(ctx context.Context, client *ws.Client) {
+	messages, err := s.RecentMessages(ctx, client.Room)
+	if err != nil {
+		log.Printf("Error loading history for room %s: %v", client.Room, err)
+		return
+	}
+
+	if err := s.messages.attachReactions(ctx, messages, client.Nickname); err != nil {
+		log.Printf("Error loading reactions for room %s: %v", client.Room, err)
+	}
+
+	for _, msg := range messages {
+		client.SendEnvelope(chatEnvelope(msg))
+	}
+}
+
+func (s *WsService) joinRoom(ctx context.Context, client *ws.Client, room string) {
+	if !ws.ValidRoom(room) {
+		client.SendEnvelope(ws.ErrorEnvelope(client.Room, "invalid_room", fmt.Sprintf("Invalid room name: %s", room)))
+		return
+	}
+	if room == client.Room {
+		return
+	}
+
+	client.Room = room
+	client.Hub.Join <- ws.Subscription{Client: client, Room: room}
+	client.SendEnvelope(ws.SystemEnvelope(room, fmt.Sprintf("You joined #%s", room)))
+	s.SendHistory(ctx, client)
+}
+
 func (s *WsService) WritingPool(ctx context.Context, client *ws.Client) {
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
@@ -139,78 +440,131 @@ func (s *WsService) WritingPool(ctx context.Context, client *ws.Client) {
 	}
 }
 
-func (s *WsService) PublishStockRequest(stockCode string) error {
-	conn := s.rabbit
-	ch, err := conn.Channel()
+func (s *WsService) PublishBotRequest(ctx context.Context, req bot.Request) error {
+	body, err := json.Marshal(req)
 	if err != nil {
 		return err
 	}
 
-	queue, err := ch.QueueDeclare(
-		"mq_stock_code_req",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		return err
-	}
-
-	body := stockCode
-	err = ch.Publish(
-		"",
-		queue.Name,
-		false,
-		false,
-		amqp091.Publishing{
-			ContentType: "text/plain",
-			Body:        []byte(body),
-		})
+	err = s.bus.Publish(ctx, bot.RequestTopic, bus.Message{
+		ContentType:   "application/json",
+		CorrelationID: req.ID,
+		ReplyTo:       bot.ResponseTopic,
+		Body:          body,
+	})
 	if err != nil {
+		log.Printf("Error publishing command /%s: %v", req.Command, err)
 		return err
 	}
 
-	log.Printf("Stock command - %s - published", stockCode)
+	log.Printf("Bot command - /%s %s - published", req.Command, strings.Join(req.Args, " "))
 	return nil
 }
 
-func (s *WsService) GetStockResponse(hub *ws.Hub) error {
-	conn := s.rabbit
+// ConsumeBotReplies routes each bot reply to the room that asked for it, or
+// to the requester only when the reply is private.
+func (s *WsService) ConsumeBotReplies(hub *ws.Hub) error {
+	return s.bus.Subscribe(bot.ResponseTopic, func(ctx context.Context, msg bus.Message) error {
+		log.Printf("Received response from queue: %s", string(msg.Body))
+		hub.Broadcast <- botReplyEnvelope(msg)
+		return nil
+	})
+}
 
-	ch, err := conn.Channel()
-	if err != nil {
-		return err
+// SetReplyTimeout changes how long a bot command may go unanswered before the
+// requester is told. Zero disables the timeout.
+func (s *WsService) SetReplyTimeout(timeout time.Duration) {
+	s.replyTimeout = timeout
+}
+
+// SetBotRateLimits changes how many bot commands a user, and all the users of
+// a room together, may send. Limits are kept per server node.
+func (s *WsService) SetBotRateLimits(user, room RateLimit) {
+	s.userLimits = newRateLimiter(user)
+	s.roomLimits = newRateLimiter(room)
+}
+
+// SetAdmins sets the nicknames allowed to run the admin commands.
+func (s *WsService) SetAdmins(nicknames []string) {
+	s.admins = make(map[string]bool, len(nicknames))
+	for _, nickname := range nicknames {
+		s.admins[nickname] = true
 	}
-	queue, err := ch.QueueDeclare(
-		"mq_stock_code_res",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		return err
+}
+
+// ObserveBroadcast marks the bot commands answered by env as done. It is
+// installed as the hub observer so replies consumed by other nodes count too.
+func (s *WsService) ObserveBroadcast(env ws.Envelope) {
+	if env.Type != ws.TypeBotReply && env.Type != ws.TypeError {
+		return
 	}
-	msgs, err := ch.Consume(
-		queue.Name,
-		"",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		return err
+	ref := env.Ref()
+	if ref == "" {
+		return
 	}
-	go func() {
-		for d := range msgs {
-			log.Printf("Received response from queue: %s", string(d.Body))
-			hub.Broadcast <- d.Body
+	if env.Type == ws.TypeBotReply {
+		var payload ws.BotReplyPayload
+		_ = json.Unmarshal(env.Payload, &payload)
+		if payload.RetryIn > 0 {
+			// the bot tries again later, wait for that attempt as well
+			s.pending.extend(ref, time.Duration(payload.RetryIn)*time.Second+s.replyTimeout)
+			return
 		}
-	}()
-	return nil
+	}
+	s.pending.resolve(ref)
+}
+
+// awaitBotReply tells the requester when the bot does not answer in time.
+func (s *WsService) awaitBotReply(inv commands.Invocation) {
+	if s.replyTimeout <= 0 {
+		return
+	}
+
+	client := inv.Client
+	room, requester, timeout := client.Room, client.Nickname, s.replyTimeout
+	s.pending.add(inv.ID, timeout, func() {
+		message := fmt.Sprintf("The bot did not answer /%s within %s, try again later", inv.Name, timeout)
+		client.Hub.Broadcast <- botErrorEnvelope(room, requester, inv.ID, "bot_timeout", message)
+	})
+}
+
+// botErrorEnvelope builds a command error only the requester receives.
+func botErrorEnvelope(room, requester, ref, code, message string) ws.Envelope {
+	env := ws.NewEnvelope(ws.TypeError, room, stockBotName, ws.ErrorPayload{
+		Code:    code,
+		Message: message,
+		Ref:     ref,
+	})
+	env.To = []string{requester}
+	return env
+}
+
+func botReplyEnvelope(msg bus.Message) ws.Envelope {
+	var reply bot.Reply
+	if err := json.Unmarshal(msg.Body, &reply); err != nil {
+		// bots that predate correlation ids send plain text to everyone
+		return ws.NewEnvelope(ws.TypeBotReply, "", stockBotName, ws.BotReplyPayload{Text: string(msg.Body)})
+	}
+	if reply.ID == "" {
+		reply.ID = msg.CorrelationID
+	}
+	if reply.Error != nil {
+		return botErrorEnvelope(reply.Room, reply.Requester, reply.ID, reply.Error.Code, reply.Error.Message)
+	}
+
+	payload := ws.BotReplyPayload{
+		Text:      reply.Text,
+		Ref:       reply.ID,
+		Requester: reply.Requester,
+		RetryIn:   reply.RetryIn,
+	}
+	if len(reply.Quotes) > 0 {
+		payload.Quotes, _ = json.Marshal(reply.Quotes)
+	}
+
+	env := ws.NewEnvelope(ws.TypeBotReply, reply.Room, stockBotName, payload)
+	if reply.Private {
+		env.To = []string{reply.Requester}
+	}
+	return env
 }
@@ -2,82 +2,284 @@ package services_test
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
-	"github.com/LuccChagas/my-chat-app/internal/models"
-	"io"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
-	"github.com/gorilla/websocket"
-	"github.com/rabbitmq/amqp091-go"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
+	db "github.com/LuccChagas/my-chat-app/db/sqlc"
+
+	"github.com/LuccChagas/my-chat-app/internal/bot"
+	"github.com/LuccChagas/my-chat-app/internal/quotes"
 	"github.com/LuccChagas/my-chat-app/internal/services"
 	ws "github.com/LuccChagas/my-chat-app/internal/websocket"
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
 )
 
-// FakeWSConn implementa a interface WSConn definida em ws
-type FakeWSConn struct {
-	readMessages [][]byte
-	readIndex    int
-	closed       bool
-}
+func TestReadingPool_NonStockMessage(t *testing.T) {
+	fakeConn := &FakeWSConn{
+		readMessages: [][]byte{[]byte("Hello")},
+	}
+
+	fakeHub := &ws.Hub{
+		Broadcast:  make(chan ws.Envelope, 10),
+		Register:   make(chan *ws.Client, 10),
+		Unregister: make(chan *ws.Client, 10),
+		Join:       make(chan ws.Subscription, 10),
+	}
+
+	client := &ws.Client{
+		Hub:      fakeHub,
+		Conn:     fakeConn,
+		Send:     make(chan []byte, 10),
+		Nickname: "TestUser",
+		Room:     ws.DefaultRoom,
+	}
 
-func (f *FakeWSConn) ReadMessage() (int, []byte, error) {
-	if f.readIndex >= len(f.readMessages) {
-		return 0, nil, fmt.Errorf("no message")
+	stored := db.Message{
+		ID:        uuid.New(),
+		Room:      ws.DefaultRoom,
+		Author:    "TestUser",
+		Content:   "Hello",
+		CreatedAt: time.Now(),
+	}
+	fakeRepo := new(FakeRepository)
+	fakeRepo.
+		On("CreateMessage", mock.Anything, mock.Anything).
+		Return(stored, nil).
+		Run(func(args mock.Arguments) {
+			arg := args.Get(1).(db.CreateMessageParams)
+			assert.Equal(t, ws.DefaultRoom, arg.Room)
+			assert.Equal(t, "TestUser", arg.Author)
+			assert.Equal(t, "Hello", arg.Content)
+		})
+	svc := newWsService(fakeRepo, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	go svc.ReadingPool(ctx, client)
+	time.Sleep(200 * time.Millisecond)
+
+	fakeRepo.AssertNumberOfCalls(t, "CreateMessage", 1)
+	select {
+	case msg := <-fakeHub.Broadcast:
+		assert.Equal(t, ws.DefaultRoom, msg.Room)
+		assert.Equal(t, ws.TypeChat, msg.Type)
+		assert.Equal(t, stored.ID.String(), msg.ID)
+		assert.True(t, strings.Contains(string(msg.Encode(ws.FormatText)), "TestUser: Hello"), "The message should contain the nickname and content")
+	default:
+		t.Error("No message was broadcasted")
 	}
-	msg := f.readMessages[f.readIndex]
-	f.readIndex++
-	return websocket.TextMessage, msg, nil
 }
 
-func (f *FakeWSConn) SetReadLimit(limit int64)            {}
-func (f *FakeWSConn) SetReadDeadline(t time.Time) error   { return nil }
-func (f *FakeWSConn) SetWriteDeadline(t time.Time) error  { return nil }
-func (f *FakeWSConn) SetPongHandler(h func(string) error) {}
-func (f *FakeWSConn) Close() error {
-	f.closed = true
-	return nil
+func TestReadingPool_StockCommand(t *testing.T) {
+	fakeConn := &FakeWSConn{
+		readMessages: [][]byte{[]byte("/stock=GOOGL.US")},
+	}
+
+	fakeHub := &ws.Hub{
+		Broadcast:  make(chan ws.Envelope, 10),
+		Register:   make(chan *ws.Client, 10),
+		Unregister: make(chan *ws.Client, 10),
+		Join:       make(chan ws.Subscription, 10),
+	}
+
+	client := &ws.Client{
+		Hub:      fakeHub,
+		Conn:     fakeConn,
+		Send:     make(chan []byte, 10),
+		Nickname: "TestUser",
+		Room:     ws.DefaultRoom,
+	}
+
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+	received := make(chan bus.Message, 1)
+	_ = memoryBus.Subscribe(bot.RequestTopic, func(ctx context.Context, msg bus.Message) error {
+		received <- msg
+		return nil
+	})
+	svc := newWsService(nil, memoryBus)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	go svc.ReadingPool(ctx, client)
+	time.Sleep(200 * time.Millisecond)
+
+	select {
+	case msg := <-fakeHub.Broadcast:
+		assert.Equal(t, ws.TypeSystem, msg.Type)
+		assert.True(t, strings.Contains(msg.Text(), "Processing command: /stock GOOGL.US"),
+			"The confirmation message should be broadcasted")
+	default:
+		t.Error("No confirmation message was broadcasted")
+	}
+
+	select {
+	case msg := <-received:
+		var req bot.Request
+		assert.NoError(t, json.Unmarshal(msg.Body, &req))
+		assert.Equal(t, "stock", req.Command)
+		assert.Equal(t, []string{"GOOGL.US"}, req.Args)
+		assert.Equal(t, ws.DefaultRoom, req.Room)
+		assert.Equal(t, "TestUser", req.Requester)
+		assert.NotEmpty(t, req.ID)
+		assert.Equal(t, req.ID, msg.CorrelationID)
+		assert.Equal(t, bot.ResponseTopic, msg.ReplyTo)
+	default:
+		t.Error("The command was not published to the bot")
+	}
 }
 
-// Para os testes de ReadingPool não precisamos usar NextWriter e WriteMessage,
-// mas podemos implementar métodos mínimos se necessário:
-func (f *FakeWSConn) NextWriter(messageType int) (io.WriteCloser, error) {
-	return &FakeWriteCloser{}, nil
+func TestReadingPool_StockCommandBusError(t *testing.T) {
+	fakeHub := newFakeHub()
+	client := newFakeClient(fakeHub, "/stock GOOGL.US")
+	svc := newWsService(nil, &FakeBus{})
+
+	runReadingPool(svc, client)
+
+	assert.Len(t, fakeHub.Broadcast, 0)
+	assert.True(t, strings.Contains(string(<-client.Send), "could not send /stock to the bot"))
 }
 
-func (f *FakeWSConn) WriteMessage(messageType int, data []byte) error {
-	return nil
+func TestReadingPool_HelpCommand(t *testing.T) {
+	fakeHub := newFakeHub()
+	client := newFakeClient(fakeHub, "/help")
+	svc := newWsService(nil, &FakeBus{})
+
+	runReadingPool(svc, client)
+
+	help := string(<-client.Send)
+	for _, usage := range []string{"/help", "/join <room>", "/leave", "/stock <stock_code>"} {
+		assert.True(t, strings.Contains(help, usage), "help should list %s", usage)
+	}
+	assert.Len(t, fakeHub.Broadcast, 0)
 }
 
-type FakeWriteCloser struct{}
+func TestReadingPool_CommandErrors(t *testing.T) {
+	fakeHub := newFakeHub()
+	client := newFakeClient(fakeHub, "/unknown", "/stock", "/join a b")
+	svc := newWsService(nil, &FakeBus{})
+
+	runReadingPool(svc, client)
 
-func (fwc *FakeWriteCloser) Write(p []byte) (int, error) {
-	return len(p), nil
+	assert.True(t, strings.Contains(string(<-client.Send), "unknown command /unknown"))
+	assert.True(t, strings.Contains(string(<-client.Send), "usage: /stock <stock_code>"))
+	assert.True(t, strings.Contains(string(<-client.Send), "usage: /join <room>"))
+	assert.Len(t, fakeHub.Broadcast, 0)
 }
-func (fwc *FakeWriteCloser) Close() error {
-	return nil
+
+func TestReadingPool_JoinRoom(t *testing.T) {
+	fakeConn := &FakeWSConn{
+		readMessages: [][]byte{[]byte("/join random"), []byte("Hello")},
+	}
+
+	fakeHub := &ws.Hub{
+		Broadcast:  make(chan ws.Envelope, 10),
+		Register:   make(chan *ws.Client, 10),
+		Unregister: make(chan *ws.Client, 10),
+		Join:       make(chan ws.Subscription, 10),
+	}
+
+	client := &ws.Client{
+		Hub:      fakeHub,
+		Conn:     fakeConn,
+		Send:     make(chan []byte, 10),
+		Nickname: "TestUser",
+		Room:     ws.DefaultRoom,
+	}
+
+	now := time.Now()
+	fakeRepo := new(FakeRepository)
+	fakeRepo.
+		On("GetRecentMessages", mock.Anything, db.GetRecentMessagesParams{Room: "random", Limit: 50}).
+		Return([]db.Message{{ID: uuid.New(), Room: "random", Author: "Other", Content: "earlier", CreatedAt: now}}, nil)
+	fakeRepo.
+		On("ListReactionCounts", mock.Anything, mock.Anything).
+		Return([]db.ListReactionCountsRow{}, nil)
+	fakeRepo.
+		On("CreateMessage", mock.Anything, mock.Anything).
+		Return(db.Message{ID: uuid.New(), Room: "random", Author: "TestUser", Content: "Hello", CreatedAt: now}, nil)
+	svc := newWsService(fakeRepo, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	go svc.ReadingPool(ctx, client)
+	time.Sleep(200 * time.Millisecond)
+
+	select {
+	case sub := <-fakeHub.Join:
+		assert.Equal(t, "random", sub.Room)
+	default:
+		t.Error("The client did not join the room")
+	}
+
+	select {
+	case msg := <-fakeHub.Broadcast:
+		assert.Equal(t, "random", msg.Room)
+		assert.Equal(t, "Hello", msg.Text())
+	default:
+		t.Error("No message was broadcasted")
+	}
+
+	fakeRepo.AssertExpectations(t)
+	assert.True(t, strings.Contains(string(<-client.Send), "You joined #random"))
+	assert.True(t, strings.Contains(string(<-client.Send), "Other: earlier"))
 }
 
-// FakeRabbitConn implementa a interface RabbitMQConnection
-type FakeRabbitConn struct{}
+func TestReadingPool_JoinInvalidRoom(t *testing.T) {
+	fakeConn := &FakeWSConn{
+		readMessages: [][]byte{[]byte("/join ../admin")},
+	}
 
-func (f *FakeRabbitConn) Channel() (*amqp091.Channel, error) {
-	return nil, fmt.Errorf("fake rabbit error")
+	fakeHub := &ws.Hub{
+		Broadcast:  make(chan ws.Envelope, 10),
+		Register:   make(chan *ws.Client, 10),
+		Unregister: make(chan *ws.Client, 10),
+		Join:       make(chan ws.Subscription, 10),
+	}
+
+	client := &ws.Client{
+		Hub:      fakeHub,
+		Conn:     fakeConn,
+		Send:     make(chan []byte, 10),
+		Nickname: "TestUser",
+		Room:     ws.DefaultRoom,
+	}
+
+	svc := newWsService(nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	go svc.ReadingPool(ctx, client)
+	time.Sleep(200 * time.Millisecond)
+
+	assert.Len(t, fakeHub.Join, 0)
+	assert.Equal(t, ws.DefaultRoom, client.Room)
+	assert.True(t, strings.Contains(string(<-client.Send), "Invalid room name"))
 }
 
-func TestReadingPool_NonStockMessage(t *testing.T) {
+func TestReadingPool_SaveErrorNotBroadcast(t *testing.T) {
 	fakeConn := &FakeWSConn{
 		readMessages: [][]byte{[]byte("Hello")},
 	}
 
 	fakeHub := &ws.Hub{
-		Broadcast:  make(chan []byte, 10),
-		Messages:   []models.Message{},
+		Broadcast:  make(chan ws.Envelope, 10),
 		Register:   make(chan *ws.Client, 10),
 		Unregister: make(chan *ws.Client, 10),
+		Join:       make(chan ws.Subscription, 10),
 	}
 
 	client := &ws.Client{
@@ -85,9 +287,12 @@ func TestReadingPool_NonStockMessage(t *testing.T) {
 		Conn:     fakeConn,
 		Send:     make(chan []byte, 10),
 		Nickname: "TestUser",
+		Room:     ws.DefaultRoom,
 	}
 
-	svc := services.NewWsService(nil, nil)
+	fakeRepo := new(FakeRepository)
+	fakeRepo.On("CreateMessage", mock.Anything, mock.Anything).Return(db.Message{}, fmt.Errorf("db down"))
+	svc := newWsService(fakeRepo, nil)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
@@ -95,24 +300,115 @@ func TestReadingPool_NonStockMessage(t *testing.T) {
 	go svc.ReadingPool(ctx, client)
 	time.Sleep(200 * time.Millisecond)
 
+	assert.Len(t, fakeHub.Broadcast, 0, "A message that was not stored must not be broadcasted")
+	if assert.Len(t, client.Send, 1, "The author is told instead of acked") {
+		assert.Contains(t, string(<-client.Send), "Error: Your message could not be sent, try again later")
+	}
+}
+
+func TestRecentMessages_ChronologicalOrder(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := newWsService(fakeRepo, nil)
+	now := time.Now()
+	newest := db.Message{ID: uuid.New(), Room: "general", Author: "b", Content: "second", CreatedAt: now}
+	oldest := db.Message{ID: uuid.New(), Room: "general", Author: "a", Content: "first", CreatedAt: now.Add(-time.Minute)}
+
+	fakeRepo.
+		On("GetRecentMessages", mock.Anything, db.GetRecentMessagesParams{Room: "general", Limit: 50}).
+		Return([]db.Message{newest, oldest}, nil)
+
+	resp, err := svc.RecentMessages(context.Background(), "general")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(resp))
+	assert.Equal(t, "first", resp[0].Content)
+	assert.Equal(t, "second", resp[1].Content)
+	assert.Equal(t, oldest.ID, resp[0].ID)
+}
+
+func TestSendHistory_Success(t *testing.T) {
+	fakeRepo := new(FakeRepository)
+	svc := newWsService(fakeRepo, nil)
+	fakeRepo.
+		On("GetRecentMessages", mock.Anything, db.GetRecentMessagesParams{Room: "general", Limit: 50}).
+		Return([]db.Message{{ID: uuid.New(), Room: "general", Author: "Other", Content: "hi", CreatedAt: time.Now()}}, nil)
+	fakeRepo.
+		On("ListReactionCounts", mock.Anything, mock.Anything).
+		Return([]db.ListReactionCountsRow{}, nil)
+
+	client := &ws.Client{
+		Send:     make(chan []byte, 10),
+		Nickname: "TestUser",
+		Room:     ws.DefaultRoom,
+	}
+
+	svc.SendHistory(context.Background(), client)
+
+	assert.Len(t, client.Send, 1)
+	assert.True(t, strings.Contains(string(<-client.Send), "Other: hi"))
+}
+
+func TestReadingPool_JSONEnvelope(t *testing.T) {
+	fakeConn := &FakeWSConn{
+		readMessages: [][]byte{[]byte(`{"v":1,"type":"chat","id":"client-1","payload":{"text":"Hello"}}`)},
+	}
+
+	fakeHub := &ws.Hub{
+		Broadcast:  make(chan ws.Envelope, 10),
+		Register:   make(chan *ws.Client, 10),
+		Unregister: make(chan *ws.Client, 10),
+		Join:       make(chan ws.Subscription, 10),
+	}
+
+	client := &ws.Client{
+		Hub:      fakeHub,
+		Conn:     fakeConn,
+		Send:     make(chan []byte, 10),
+		Nickname: "TestUser",
+		Room:     ws.DefaultRoom,
+		Format:   ws.FormatJSON,
+	}
+
+	stored := db.Message{ID: uuid.New(), Room: ws.DefaultRoom, Author: "TestUser", Content: "Hello", CreatedAt: time.Now()}
+	fakeRepo := new(FakeRepository)
+	fakeRepo.On("CreateMessage", mock.Anything, mock.Anything).Return(stored, nil)
+	svc := newWsService(fakeRepo, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	go svc.ReadingPool(ctx, client)
+	time.Sleep(200 * time.Millisecond)
+
+	var ack ws.Envelope
+	assert.NoError(t, json.Unmarshal(<-client.Send, &ack))
+	assert.Equal(t, ws.TypeAck, ack.Type)
+	assert.Equal(t, stored.ID.String(), ack.ID)
+	assert.JSONEq(t, `{"ref":"client-1"}`, string(ack.Payload))
+
 	select {
 	case msg := <-fakeHub.Broadcast:
-		assert.True(t, strings.Contains(string(msg), "TestUser: Hello"), "The message should contain the nickname and content")
+		assert.Equal(t, ws.ProtocolVersion, msg.Version)
+		assert.Equal(t, stored.ID.String(), msg.ID)
+		assert.Equal(t, "Hello", msg.Text())
 	default:
 		t.Error("No message was broadcasted")
 	}
 }
 
-func TestReadingPool_StockCommand(t *testing.T) {
+func TestReadingPool_InvalidEnvelopes(t *testing.T) {
 	fakeConn := &FakeWSConn{
-		readMessages: [][]byte{[]byte("/stock=GOOGL.US")},
+		readMessages: [][]byte{
+			[]byte(`{"type":`),
+			[]byte(`{"v":1,"type":"unknown"}`),
+			[]byte(strings.Repeat("a", 281)),
+		},
 	}
 
 	fakeHub := &ws.Hub{
-		Broadcast:  make(chan []byte, 10),
-		Messages:   []models.Message{},
+		Broadcast:  make(chan ws.Envelope, 10),
 		Register:   make(chan *ws.Client, 10),
 		Unregister: make(chan *ws.Client, 10),
+		Join:       make(chan ws.Subscription, 10),
 	}
 
 	client := &ws.Client{
@@ -120,10 +416,11 @@ func TestReadingPool_StockCommand(t *testing.T) {
 		Conn:     fakeConn,
 		Send:     make(chan []byte, 10),
 		Nickname: "TestUser",
+		Room:     ws.DefaultRoom,
+		Format:   ws.FormatJSON,
 	}
 
-	fakeRabbit := &FakeRabbitConn{}
-	svc := services.NewWsService(nil, fakeRabbit)
+	svc := newWsService(nil, nil)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
@@ -131,11 +428,564 @@ func TestReadingPool_StockCommand(t *testing.T) {
 	go svc.ReadingPool(ctx, client)
 	time.Sleep(200 * time.Millisecond)
 
+	assert.Len(t, fakeHub.Broadcast, 0)
+	for _, code := range []string{"bad_request", "unsupported_type", "message_too_long"} {
+		var env ws.Envelope
+		assert.NoError(t, json.Unmarshal(<-client.Send, &env))
+		assert.Equal(t, ws.TypeError, env.Type)
+		assert.Contains(t, string(env.Payload), code)
+	}
+}
+
+func TestReadingPool_PlainTextBraces(t *testing.T) {
+	fakeHub := newFakeHub()
+	client := newFakeClient(fakeHub, "{hi", "{}")
+
+	fakeRepo := new(FakeRepository)
+	for _, text := range []string{"{hi", "{}"} {
+		stored := db.Message{ID: uuid.New(), Room: ws.DefaultRoom, Author: "TestUser", Content: text, CreatedAt: time.Now()}
+		fakeRepo.
+			On("CreateMessage", mock.Anything, mock.MatchedBy(func(arg db.CreateMessageParams) bool { return arg.Content == text })).
+			Return(stored, nil)
+	}
+	svc := newWsService(fakeRepo, nil)
+
+	runReadingPool(svc, client)
+
+	// plain-text clients did not negotiate JSON, their braces are chat text
+	for _, text := range []string{"{hi", "{}"} {
+		select {
+		case msg := <-fakeHub.Broadcast:
+			assert.Equal(t, ws.TypeChat, msg.Type)
+			assert.Equal(t, text, msg.Text())
+		default:
+			t.Errorf("%q was not broadcasted", text)
+		}
+	}
+	assert.Len(t, client.Send, 0, "No error is sent back")
+}
+
+func TestPublishBotRequest_MemoryBus(t *testing.T) {
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+
+	received := make(chan bus.Message, 1)
+	err := memoryBus.Subscribe(bot.RequestTopic, func(ctx context.Context, msg bus.Message) error {
+		received <- msg
+		return nil
+	})
+	assert.NoError(t, err)
+
+	svc := newWsService(nil, memoryBus)
+	assert.NoError(t, svc.PublishBotRequest(context.Background(), bot.Request{ID: "req-1", Command: "stock", Args: []string{"aapl.us"}}))
+
 	select {
-	case msg := <-fakeHub.Broadcast:
-		assert.True(t, strings.Contains(string(msg), "Processing command for stock code: GOOGL.US"),
-			"The confirmation message should be broadcasted")
+	case msg := <-received:
+		assert.Equal(t, "req-1", msg.CorrelationID)
+		assert.JSONEq(t, `{"id":"req-1","command":"stock","args":["aapl.us"],"room":"","requester":""}`, string(msg.Body))
+	case <-time.After(time.Second):
+		t.Error("The stock request was not published")
+	}
+}
+
+func TestConsumeBotReplies_LegacyTextReply(t *testing.T) {
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+
+	fakeHub := &ws.Hub{
+		Broadcast: make(chan ws.Envelope, 10),
+	}
+
+	svc := newWsService(nil, memoryBus)
+	assert.NoError(t, svc.ConsumeBotReplies(fakeHub))
+
+	err := memoryBus.Publish(context.Background(), bot.ResponseTopic, bus.Message{Body: []byte("AAPL.US quote is $200 per share")})
+	assert.NoError(t, err)
+
+	select {
+	case env := <-fakeHub.Broadcast:
+		assert.Equal(t, ws.TypeBotReply, env.Type)
+		assert.Empty(t, env.Room)
+		assert.Equal(t, "AAPL.US quote is $200 per share", env.Text())
+	case <-time.After(time.Second):
+		t.Error("The stock reply was not broadcasted")
+	}
+}
+
+func TestConsumeBotReplies_RoutesReplies(t *testing.T) {
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+
+	fakeHub := &ws.Hub{
+		Broadcast: make(chan ws.Envelope, 10),
+	}
+
+	svc := newWsService(nil, memoryBus)
+	assert.NoError(t, svc.ConsumeBotReplies(fakeHub))
+
+	replies := []bot.Reply{
+		{ID: "req-1", Room: "random", Requester: "alice", Text: "AAPL.US quote is $200 per share"},
+		{ID: "req-2", Room: "random", Requester: "bob", Private: true, Text: "only for bob"},
+	}
+	for _, reply := range replies {
+		body, _ := json.Marshal(reply)
+		assert.NoError(t, memoryBus.Publish(context.Background(), bot.ResponseTopic, bus.Message{CorrelationID: reply.ID, Body: body}))
+	}
+
+	for _, reply := range replies {
+		select {
+		case env := <-fakeHub.Broadcast:
+			var payload ws.BotReplyPayload
+			assert.NoError(t, json.Unmarshal(env.Payload, &payload))
+			assert.Equal(t, reply.ID, payload.Ref)
+			assert.Equal(t, reply.Requester, payload.Requester)
+			assert.Equal(t, reply.Room, env.Room)
+			if reply.Private {
+				assert.Equal(t, []string{reply.Requester}, env.To)
+			} else {
+				assert.Empty(t, env.To)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("The bot reply was not routed")
+		}
+	}
+}
+
+func TestConsumeBotReplies_ErrorReply(t *testing.T) {
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+
+	fakeHub := &ws.Hub{
+		Broadcast: make(chan ws.Envelope, 10),
+	}
+
+	svc := newWsService(nil, memoryBus)
+	assert.NoError(t, svc.ConsumeBotReplies(fakeHub))
+
+	body, _ := json.Marshal(bot.Reply{
+		ID:        "req-1",
+		Room:      "random",
+		Requester: "alice",
+		Private:   true,
+		Error:     &bot.ReplyError{Code: bot.CodeUnknownSymbol, Message: "Unknown stock symbol XXX.US"},
+	})
+	assert.NoError(t, memoryBus.Publish(context.Background(), bot.ResponseTopic, bus.Message{Body: body}))
+
+	select {
+	case env := <-fakeHub.Broadcast:
+		var payload ws.ErrorPayload
+		assert.NoError(t, json.Unmarshal(env.Payload, &payload))
+		assert.Equal(t, ws.TypeError, env.Type)
+		assert.Equal(t, "random", env.Room)
+		assert.Equal(t, []string{"alice"}, env.To)
+		assert.Equal(t, bot.CodeUnknownSymbol, payload.Code)
+		assert.Equal(t, "Unknown stock symbol XXX.US", payload.Message)
+		assert.Equal(t, "req-1", payload.Ref)
+	case <-time.After(time.Second):
+		t.Fatal("The bot error was not routed")
+	}
+}
+
+func TestReadingPool_BotReplyTimeout(t *testing.T) {
+	fakeHub := newFakeHub()
+	client := newFakeClient(fakeHub, "/stock GOOGL.US")
+
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+	svc := newWsService(nil, memoryBus)
+	svc.SetReplyTimeout(50 * time.Millisecond)
+
+	runReadingPool(svc, client)
+
+	assert.Equal(t, ws.TypeSystem, (<-fakeHub.Broadcast).Type)
+	select {
+	case env := <-fakeHub.Broadcast:
+		var payload ws.ErrorPayload
+		assert.NoError(t, json.Unmarshal(env.Payload, &payload))
+		assert.Equal(t, ws.TypeError, env.Type)
+		assert.Equal(t, []string{"TestUser"}, env.To)
+		assert.Equal(t, "bot_timeout", payload.Code)
+		assert.NotEmpty(t, payload.Ref)
 	default:
-		t.Error("No confirmation message was broadcasted")
+		t.Error("The requester was not told about the timeout")
 	}
 }
+
+func TestObserveBroadcast_CancelsTimeout(t *testing.T) {
+	fakeHub := newFakeHub()
+	client := newFakeClient(fakeHub, "/stock GOOGL.US")
+
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+	received := make(chan bus.Message, 1)
+	_ = memoryBus.Subscribe(bot.RequestTopic, func(ctx context.Context, msg bus.Message) error {
+		received <- msg
+		return nil
+	})
+	svc := newWsService(nil, memoryBus)
+	svc.SetReplyTimeout(300 * time.Millisecond)
+
+	runReadingPool(svc, client)
+
+	msg := <-received
+	svc.ObserveBroadcast(ws.NewEnvelope(ws.TypeBotReply, ws.DefaultRoom, "StockBot", ws.BotReplyPayload{
+		Text: "GOOGL.US quote is $100 per share",
+		Ref:  msg.CorrelationID,
+	}))
+	time.Sleep(200 * time.Millisecond)
+
+	assert.Equal(t, ws.TypeSystem, (<-fakeHub.Broadcast).Type)
+	assert.Len(t, fakeHub.Broadcast, 0, "An answered command must not time out")
+}
+
+func TestObserveBroadcast_RetryNoticeExtendsTimeout(t *testing.T) {
+	fakeHub := newFakeHub()
+	client := newFakeClient(fakeHub, "/stock GOOGL.US")
+
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+	received := make(chan bus.Message, 1)
+	_ = memoryBus.Subscribe(bot.RequestTopic, func(ctx context.Context, msg bus.Message) error {
+		received <- msg
+		return nil
+	})
+	svc := newWsService(nil, memoryBus)
+	svc.SetReplyTimeout(400 * time.Millisecond)
+
+	runReadingPool(svc, client)
+
+	msg := <-received
+	svc.ObserveBroadcast(ws.NewEnvelope(ws.TypeBotReply, ws.DefaultRoom, "StockBot", ws.BotReplyPayload{
+		Text:    "The quote service did not answer, retrying in 1s",
+		Ref:     msg.CorrelationID,
+		RetryIn: 1,
+	}))
+	time.Sleep(600 * time.Millisecond)
+
+	assert.Equal(t, ws.TypeSystem, (<-fakeHub.Broadcast).Type)
+	assert.Len(t, fakeHub.Broadcast, 0, "A retried command must not time out before its next attempt")
+
+	svc.ObserveBroadcast(ws.NewEnvelope(ws.TypeBotReply, ws.DefaultRoom, "StockBot", ws.BotReplyPayload{
+		Text: "GOOGL.US quote is $100 per share",
+		Ref:  msg.CorrelationID,
+	}))
+	time.Sleep(1500 * time.Millisecond)
+	assert.Len(t, fakeHub.Broadcast, 0, "An answered command must not time out")
+}
+
+func TestReadingPool_DeadLetterCommandAdminOnly(t *testing.T) {
+	fakeHub := newFakeHub()
+	client := newFakeClient(fakeHub, "/dlq")
+
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+	svc := newWsService(nil, memoryBus)
+
+	runReadingPool(svc, client)
+
+	assert.True(t, strings.Contains(string(<-client.Send), "/dlq is reserved to admins"))
+}
+
+func TestReadingPool_DeadLetterCommand(t *testing.T) {
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+	assert.NoError(t, memoryBus.SetRetryPolicy(bot.RequestTopic, bus.RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}))
+
+	deliveries := make(chan bus.Message, 10)
+	var failing atomic.Bool
+	failing.Store(true)
+	_ = memoryBus.Subscribe(bot.RequestTopic, func(ctx context.Context, msg bus.Message) error {
+		deliveries <- msg
+		if failing.Load() {
+			return fmt.Errorf("upstream is down")
+		}
+		return nil
+	})
+
+	svc := newWsService(nil, memoryBus)
+	svc.SetAdmins([]string{"TestUser"})
+	assert.NoError(t, svc.PublishBotRequest(context.Background(), bot.Request{
+		ID: "req-1", Command: "stock", Args: []string{"aapl.us"}, Room: "general", Requester: "alice",
+	}))
+
+	assert.Equal(t, 1, bus.Attempt(<-deliveries))
+	assert.Equal(t, 2, bus.Attempt(<-deliveries))
+	time.Sleep(50 * time.Millisecond)
+
+	fakeHub := newFakeHub()
+	client := newFakeClient(fakeHub, "/dlq")
+	runReadingPool(svc, client)
+
+	listing := string(<-client.Send)
+	assert.True(t, strings.Contains(listing, "/stock aapl.us by alice in #general after 2 attempts: upstream is down"), listing)
+
+	failing.Store(false)
+	client = newFakeClient(fakeHub, "/dlq replay")
+	runReadingPool(svc, client)
+
+	assert.True(t, strings.Contains(string(<-client.Send), "Replayed 1 failed bot requests"))
+	replayed := <-deliveries
+	assert.Equal(t, 1, bus.Attempt(replayed))
+	assert.Empty(t, replayed.Headers[bus.ErrorHeader])
+
+	msgs, err := memoryBus.DeadLetters(context.Background(), bot.RequestTopic, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, msgs)
+}
+
+func TestConsumeBotReplies_QuoteCards(t *testing.T) {
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+
+	fakeHub := &ws.Hub{
+		Broadcast: make(chan ws.Envelope, 10),
+	}
+
+	svc := newWsService(nil, memoryBus)
+	assert.NoError(t, svc.ConsumeBotReplies(fakeHub))
+
+	card := bot.QuoteCard{Quote: quotes.Quote{
+		Symbol: "AAPL.US", Date: "2025-01-10", Time: "22:00:05",
+		Open: 240.01, High: 240.16, Low: 233, Close: 236.85, Volume: 61710856,
+	}}
+	body, _ := json.Marshal(bot.Reply{
+		ID: "req-1", Room: "general", Requester: "alice",
+		Text: "AAPL.US quote is $236.85 per share", Quotes: []bot.QuoteCard{card},
+	})
+	assert.NoError(t, memoryBus.Publish(context.Background(), bot.ResponseTopic, bus.Message{Body: body}))
+
+	select {
+	case env := <-fakeHub.Broadcast:
+		var payload struct {
+			Text   string          `json:"text"`
+			Quotes []bot.QuoteCard `json:"quotes"`
+		}
+		assert.NoError(t, json.Unmarshal(env.Payload, &payload))
+		assert.Equal(t, ws.TypeBotReply, env.Type)
+		assert.Equal(t, "AAPL.US quote is $236.85 per share", payload.Text)
+		assert.Equal(t, []bot.QuoteCard{card}, payload.Quotes)
+	case <-time.After(time.Second):
+		t.Fatal("The bot reply was not routed")
+	}
+}
+
+func TestReadingPool_BotCommandUserRateLimit(t *testing.T) {
+	fakeHub := newFakeHub()
+	client := newFakeClient(fakeHub, "/stock aapl.us", "/stock aapl.us", "/stock aapl.us")
+
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+	var published atomic.Int32
+	_ = memoryBus.Subscribe(bot.RequestTopic, func(ctx context.Context, msg bus.Message) error {
+		published.Add(1)
+		return nil
+	})
+	svc := newWsService(nil, memoryBus)
+	svc.SetBotRateLimits(services.RateLimit{Burst: 2, Interval: time.Minute}, services.RateLimit{})
+
+	runReadingPool(svc, client)
+
+	assert.Equal(t, int32(2), published.Load(), "Only the burst should reach the bot")
+	assert.Len(t, fakeHub.Broadcast, 2)
+	assert.True(t, strings.Contains(string(<-client.Send), "Slow down!"),
+		"The requester should be told to slow down")
+}
+
+func TestReadingPool_BotCommandRoomRateLimit(t *testing.T) {
+	fakeHub := newFakeHub()
+	first := newFakeClient(fakeHub, "/stock aapl.us")
+	second := newFakeClient(fakeHub, "/stock aapl.us")
+	second.Nickname = "OtherUser"
+
+	memoryBus := bus.NewMemoryBus()
+	defer memoryBus.Close()
+	svc := newWsService(nil, memoryBus)
+	svc.SetBotRateLimits(services.DefaultUserRateLimit, services.RateLimit{Burst: 1, Interval: time.Minute})
+
+	runReadingPool(svc, first)
+	runReadingPool(svc, second)
+
+	assert.Len(t, fakeHub.Broadcast, 1, "The room allows one command")
+	assert.Len(t, first.Send, 0)
+	assert.True(t, strings.Contains(string(<-second.Send), "Slow down!"))
+}
+
+func TestReadingPool_DirectMessage(t *testing.T) {
+	fakeHub := newFakeHub()
+	client := newFakeClient(fakeHub,
+		`{"v":1,"type":"dm","id":"c1","to":["alice"],"payload":{"text":"hi"}}`,
+		`{"v":1,"type":"dm","to":["alice","carol"],"payload":{"text":"hi"}}`)
+	client.Format = ws.FormatJSON
+
+	fakeRepo := new(FakeRepository)
+	channelID := uuid.New()
+	messageID := uuid.New()
+	fakeRepo.On("GetUserByNickname", mock.Anything, "alice").Return(db.User{NickName: "alice"}, nil)
+	fakeRepo.On("UpsertDMChannel", mock.Anything, mock.Anything).Return(db.DmChannel{ID: channelID, UserA: "TestUser", UserB: "alice"}, nil)
+	fakeRepo.On("CreateDirectMessage", mock.Anything, mock.Anything).Return(db.DirectMessage{
+		ID: messageID, ChannelID: channelID, Author: "TestUser", Content: "hi", CreatedAt: time.Now(),
+	}, nil)
+	svc := newWsService(fakeRepo, &FakeBus{})
+
+	runReadingPool(svc, client)
+
+	env := <-fakeHub.Broadcast
+	assert.Equal(t, ws.TypeDirect, env.Type)
+	assert.Equal(t, messageID.String(), env.ID)
+	assert.Equal(t, []string{"TestUser", "alice"}, env.To, "Both participants get the message")
+	assert.Empty(t, env.Room)
+	assert.Equal(t, "hi", env.Text())
+
+	var ack ws.Envelope
+	assert.NoError(t, json.Unmarshal(<-client.Send, &ack))
+	assert.Equal(t, ws.TypeAck, ack.Type)
+	assert.Equal(t, "c1", ack.Ref())
+
+	var rejected ws.Envelope
+	assert.NoError(t, json.Unmarshal(<-client.Send, &rejected))
+	assert.Equal(t, ws.TypeError, rejected.Type)
+	assert.Len(t, fakeHub.Broadcast, 0, "A message to several users is rejected")
+}
+
+func TestReadingPool_Typing(t *testing.T) {
+	fakeHub := newFakeHub()
+	// the second start is throttled, the stop is forwarded and a start after
+	// it is sent right away
+	client := newFakeClient(fakeHub,
+		`{"v":1,"type":"typing","payload":{"active":true}}`,
+		`{"v":1,"type":"typing","payload":{"active":true}}`,
+		`{"v":1,"type":"typing","payload":{"active":false}}`,
+		`{"v":1,"type":"typing","payload":{"active":true}}`,
+	)
+	svc := newWsService(nil, &FakeBus{})
+
+	runReadingPool(svc, client)
+
+	assert.Len(t, client.Send, 0)
+	if !assert.Len(t, fakeHub.Broadcast, 3) {
+		return
+	}
+	for _, active := range []bool{true, false, true} {
+		env := <-fakeHub.Broadcast
+		assert.Equal(t, ws.TypeTyping, env.Type)
+		assert.Equal(t, ws.DefaultRoom, env.Room)
+		assert.Equal(t, "TestUser", env.Author)
+
+		var payload ws.TypingPayload
+		assert.NoError(t, json.Unmarshal(env.Payload, &payload))
+		assert.Equal(t, active, payload.Active)
+		if active {
+			assert.Equal(t, 5000, payload.TTL)
+		} else {
+			assert.Zero(t, payload.TTL)
+		}
+	}
+}
+
+func TestReadingPool_EditAndDeleteMessage(t *testing.T) {
+	fakeHub := newFakeHub()
+	messageID := uuid.New()
+	client := newFakeClient(fakeHub,
+		`{"v":1,"type":"edit","id":"c1","payload":{"ref":"`+messageID.String()+`","text":"hello"}}`,
+		`{"v":1,"type":"delete","id":"c2","payload":{"ref":"`+messageID.String()+`"}}`,
+		`{"v":1,"type":"delete","payload":{"ref":"not-an-id"}}`)
+	client.Format = ws.FormatJSON
+
+	now := time.Now()
+	stored := db.Message{ID: messageID, Room: ws.DefaultRoom, Author: "TestUser", Content: "helo", CreatedAt: now}
+	edited := stored
+	edited.Content = "hello"
+	edited.EditedAt = sql.NullTime{Time: now, Valid: true}
+	tombstone := edited
+	tombstone.Content = ""
+	tombstone.DeletedAt = sql.NullTime{Time: now, Valid: true}
+
+	fakeRepo := new(FakeRepository)
+	fakeRepo.On("GetMessage", mock.Anything, messageID).Return(stored, nil)
+	fakeRepo.On("EditMessage", mock.Anything, mock.Anything).Return(edited, nil)
+	fakeRepo.On("DeleteMessage", mock.Anything, mock.Anything).Return(tombstone, nil)
+	svc := newWsService(fakeRepo, &FakeBus{})
+
+	runReadingPool(svc, client)
+
+	if !assert.Len(t, fakeHub.Broadcast, 2) {
+		return
+	}
+	update := <-fakeHub.Broadcast
+	assert.Equal(t, ws.TypeUpdate, update.Type)
+	assert.Equal(t, messageID.String(), update.ID, "The update replaces the message")
+	assert.Equal(t, ws.DefaultRoom, update.Room)
+	assert.Equal(t, "hello", update.Text())
+
+	update = <-fakeHub.Broadcast
+	var payload ws.TextPayload
+	assert.NoError(t, json.Unmarshal(update.Payload, &payload))
+	assert.True(t, payload.Deleted)
+	assert.Empty(t, payload.Text)
+
+	for _, ref := range []string{"c1", "c2"} {
+		var ack ws.Envelope
+		assert.NoError(t, json.Unmarshal(<-client.Send, &ack))
+		assert.Equal(t, ws.TypeAck, ack.Type)
+		assert.Equal(t, ref, ack.Ref())
+	}
+
+	var rejected ws.Envelope
+	assert.NoError(t, json.Unmarshal(<-client.Send, &rejected))
+	assert.Equal(t, ws.TypeError, rejected.Type)
+}
+
+func TestReadingPool_Reaction(t *testing.T) {
+	fakeHub := newFakeHub()
+	messageID := uuid.New()
+	client := newFakeClient(fakeHub,
+		`{"v":1,"type":"reaction","id":"c1","payload":{"ref":"`+messageID.String()+`","emoji":"👍"}}`,
+		`{"v":1,"type":"reaction","id":"c2","payload":{"ref":"`+messageID.String()+`","emoji":"👍"}}`)
+	client.Format = ws.FormatJSON
+
+	fakeRepo := new(FakeRepository)
+	fakeRepo.On("GetMessage", mock.Anything, messageID).
+		Return(db.Message{ID: messageID, Room: "random", Author: "Other", Content: "hi", CreatedAt: time.Now()}, nil)
+	fakeRepo.On("AddReaction", mock.Anything, mock.Anything).Return(int64(1), nil).Once()
+	fakeRepo.On("AddReaction", mock.Anything, mock.Anything).Return(int64(0), nil).Once()
+	fakeRepo.On("CountReactions", mock.Anything, mock.Anything).Return(int64(1), nil)
+	svc := newWsService(fakeRepo, &FakeBus{})
+
+	runReadingPool(svc, client)
+
+	// the second reaction was already there, so nothing changed
+	if !assert.Len(t, fakeHub.Broadcast, 1) {
+		return
+	}
+	env := <-fakeHub.Broadcast
+	assert.Equal(t, ws.TypeReaction, env.Type)
+	assert.Equal(t, "random", env.Room, "The reaction goes to the message room")
+	assert.Equal(t, "TestUser", env.Author)
+
+	var payload ws.ReactionPayload
+	assert.NoError(t, json.Unmarshal(env.Payload, &payload))
+	assert.Equal(t, messageID.String(), payload.Ref)
+	assert.Equal(t, "👍", payload.Emoji)
+	assert.Equal(t, 1, payload.Count)
+
+	for _, ref := range []string{"c1", "c2"} {
+		var ack ws.Envelope
+		assert.NoError(t, json.Unmarshal(<-client.Send, &ack))
+		assert.Equal(t, ws.TypeAck, ack.Type)
+		assert.Equal(t, ref, ack.Ref())
+	}
+}
+
+func TestReadingPool_FullSendBuffer(t *testing.T) {
+	fakeHub := newFakeHub()
+	client := newFakeClient(fakeHub, "/help", "/help", "/help")
+	client.Send = make(chan []byte, 1)
+	svc := newWsService(nil, &FakeBus{})
+
+	assert.NotPanics(t, func() { runReadingPool(svc, client) })
+
+	// the first answer was queued, the next ones closed the slow client
+	<-client.Send
+	_, ok := <-client.Send
+	assert.False(t, ok)
+}
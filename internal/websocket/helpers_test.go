@@ -0,0 +1,99 @@
+package websocket_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	ws "github.com/LuccChagas/my-chat-app/internal/websocket"
+)
+
+func newTestHub() *ws.Hub {
+	hub := ws.NewHub()
+	hub.IdleAfter = 0
+	go hub.Run()
+	return hub
+}
+
+func newTestClient(hub *ws.Hub, nickname string, buffer int) *ws.Client {
+	return &ws.Client{
+		Hub:      hub,
+		Send:     make(chan []byte, buffer),
+		Nickname: nickname,
+		Room:     ws.DefaultRoom,
+		Format:   ws.FormatJSON,
+	}
+}
+
+// drain reads the frames queued for client until Send is empty, and
+// reports whether Send was closed.
+func drain(client *ws.Client) (frames [][]byte, closed bool) {
+	for {
+		select {
+		case frame, ok := <-client.Send:
+			if !ok {
+				return frames, true
+			}
+			frames = append(frames, frame)
+		default:
+			return frames, false
+		}
+	}
+}
+
+// presenceEvents returns the presence changes queued for client, as
+// "nickname status" strings.
+func presenceEvents(t *testing.T, client *ws.Client) []string {
+	frames, _ := drain(client)
+	var events []string
+	for _, frame := range frames {
+		var env ws.Envelope
+		assert.NoError(t, json.Unmarshal(frame, &env))
+		if env.Type != ws.TypePresence {
+			continue
+		}
+		var payload ws.PresencePayload
+		assert.NoError(t, json.Unmarshal(env.Payload, &payload))
+		events = append(events, payload.Nickname+" "+payload.Status)
+	}
+	return events
+}
+
+func onlineTabs(hub *ws.Hub) map[string]int {
+	tabs := make(map[string]int)
+	for _, p := range hub.Online() {
+		tabs[p.Nickname] = p.Tabs
+	}
+	return tabs
+}
+
+type FakeRelay struct {
+	mock.Mock
+}
+
+func (r *FakeRelay) Publish(env ws.Envelope) error {
+	args := r.Called(env)
+	return args.Error(0)
+}
+
+// snapshots returns the presence snapshots relayed so far. The hub must
+// have handled them, e.g. by a call to Online.
+func (r *FakeRelay) snapshots(t *testing.T) []ws.PresenceSyncPayload {
+	var snapshots []ws.PresenceSyncPayload
+	for _, call := range r.Calls {
+		env := call.Arguments.Get(0).(ws.Envelope)
+		if env.Type != ws.TypePresenceSync {
+			continue
+		}
+		var payload ws.PresenceSyncPayload
+		assert.NoError(t, json.Unmarshal(env.Payload, &payload))
+		snapshots = append(snapshots, payload)
+	}
+	return snapshots
+}
+
+func presenceSync(node string, users ...ws.Presence) ws.Envelope {
+	return ws.NewEnvelope(ws.TypePresenceSync, "", "", ws.PresenceSyncPayload{Node: node, Users: users})
+}
@@ -0,0 +1,286 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
+
+// DefaultIdleAfter is how long a user may stay silent before being shown as
+// idle.
+const DefaultIdleAfter = 5 * time.Minute
+
+// maxIdleCheck is the longest wait between two idle checks.
+const maxIdleCheck = 15 * time.Second
+
+// A clustered node relays its presence snapshot every presenceSyncInterval
+// and whenever it changes. The users of a node that stayed silent for
+// presenceExpiry are dropped, so a crashed node does not keep them online.
+const (
+	presenceSyncInterval = 10 * time.Second
+	presenceExpiry       = 3 * presenceSyncInterval
+)
+
+// Presence statuses, sent in PresencePayload and listed by Online.
+const (
+	PresenceOnline  = "online"
+	PresenceIdle    = "idle"
+	PresenceOffline = "offline"
+)
+
+// Presence is one connected user, however many tabs they have open.
+type Presence struct {
+	Nickname string    `json:"nickname"`
+	Status   string    `json:"status"`
+	Since    time.Time `json:"since"`
+	Tabs     int       `json:"tabs"`
+}
+
+// PresencePayload tells that a user joined (online), went idle, came back
+// (online) or left (offline).
+type PresencePayload struct {
+	Nickname string `json:"nickname"`
+	Status   string `json:"status"`
+}
+
+// OnlinePayload lists the connected users; it is sent to new connections.
+type OnlinePayload struct {
+	Users []Presence `json:"users"`
+}
+
+// PresenceSyncPayload lists the users connected to Node. It is relayed to
+// the other nodes in TypePresenceSync envelopes.
+type PresenceSyncPayload struct {
+	Node  string     `json:"node"`
+	Users []Presence `json:"users"`
+}
+
+// nodePresence is the last snapshot received from another node.
+type nodePresence struct {
+	users []Presence
+	seen  time.Time
+}
+
+// Touch records activity of the client, which keeps its user from going
+// idle. It is safe to call from any goroutine.
+func (c *Client) Touch() {
+	c.lastActive.Store(time.Now().UnixNano())
+}
+
+func (c *Client) lastActivity() time.Time {
+	return time.Unix(0, c.lastActive.Load())
+}
+
+// Online returns the connected users, sorted by nickname. With a Relay the
+// users of the other nodes are included. It must only be called while Run is
+// running.
+func (h *Hub) Online() []Presence {
+	reply := make(chan []Presence, 1)
+	h.presenceQueries <- reply
+	return <-reply
+}
+
+// online lists the merged presence entries; it runs in the hub goroutine.
+func (h *Hub) online() []Presence {
+	merged := h.merged()
+	users := make([]Presence, 0, len(merged))
+	for _, p := range merged {
+		users = append(users, p)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Nickname < users[j].Nickname })
+	return users
+}
+
+// localPresence lists the users connected to this node.
+func (h *Hub) localPresence() []Presence {
+	users := make([]Presence, 0, len(h.presence))
+	for _, p := range h.presence {
+		users = append(users, *p)
+	}
+	return users
+}
+
+// merged combines the users of this node with those of the other nodes. A
+// user is online when any node sees them online, and their tabs add up.
+func (h *Hub) merged() map[string]Presence {
+	merged := make(map[string]Presence, len(h.presence))
+	add := func(p Presence) {
+		current, ok := merged[p.Nickname]
+		if !ok {
+			merged[p.Nickname] = p
+			return
+		}
+		current.Tabs += p.Tabs
+		switch {
+		case current.Status == p.Status && p.Since.Before(current.Since):
+			current.Since = p.Since
+		case current.Status != p.Status && p.Status == PresenceOnline:
+			current.Status, current.Since = p.Status, p.Since
+		}
+		merged[p.Nickname] = current
+	}
+
+	for _, p := range h.presence {
+		add(*p)
+	}
+	for _, node := range h.remote {
+		for _, p := range node.users {
+			add(p)
+		}
+	}
+	return merged
+}
+
+// arrive counts a new tab of client's user.
+func (h *Hub) arrive(client *Client) {
+	client.Touch()
+
+	h.localChanged = true
+	p, ok := h.presence[client.Nickname]
+	if ok {
+		p.Tabs++
+		return
+	}
+	if h.presence == nil {
+		h.presence = make(map[string]*Presence)
+	}
+	h.presence[client.Nickname] = &Presence{
+		Nickname: client.Nickname,
+		Status:   PresenceOnline,
+		Since:    time.Now(),
+		Tabs:     1,
+	}
+}
+
+// depart forgets a tab of client's user, and the user with their last tab.
+func (h *Hub) depart(client *Client) {
+	p, ok := h.presence[client.Nickname]
+	if !ok {
+		return
+	}
+	h.localChanged = true
+	if p.Tabs--; p.Tabs > 0 {
+		return
+	}
+	delete(h.presence, client.Nickname)
+}
+
+// checkIdle moves the users whose tabs were all silent for IdleAfter to
+// idle, and the idle users who became active back to online.
+func (h *Hub) checkIdle(now time.Time) {
+	lastActive := make(map[string]time.Time, len(h.presence))
+	for client := range h.Clients {
+		if active := client.lastActivity(); active.After(lastActive[client.Nickname]) {
+			lastActive[client.Nickname] = active
+		}
+	}
+
+	for nickname, p := range h.presence {
+		idle := now.Sub(lastActive[nickname]) >= h.IdleAfter
+		switch {
+		case idle && p.Status == PresenceOnline:
+			p.Status, p.Since = PresenceIdle, now
+			h.localChanged = true
+		case !idle && p.Status == PresenceIdle:
+			p.Status, p.Since = PresenceOnline, now
+			h.localChanged = true
+		}
+	}
+}
+
+// syncRemote stores the snapshot relayed by another node. A node heard of
+// for the first time is sent this node's snapshot right away, so it does
+// not wait for the next sync to know who is online.
+func (h *Hub) syncRemote(env Envelope, now time.Time) {
+	var payload PresenceSyncPayload
+	if err := json.Unmarshal(env.Payload, &payload); err != nil || payload.Node == h.NodeID {
+		return
+	}
+
+	if h.remote == nil {
+		h.remote = make(map[string]*nodePresence)
+	}
+	_, known := h.remote[payload.Node]
+	h.remote[payload.Node] = &nodePresence{users: payload.Users, seen: now}
+	h.remoteChanged = true
+	if !known {
+		h.sendSnapshot()
+	}
+}
+
+// expireRemote drops the nodes that did not send a snapshot for
+// presenceExpiry.
+func (h *Hub) expireRemote(now time.Time) {
+	for node, p := range h.remote {
+		if now.Sub(p.seen) >= presenceExpiry {
+			delete(h.remote, node)
+			h.remoteChanged = true
+		}
+	}
+}
+
+// sendSnapshot relays the users of this node to the other nodes.
+func (h *Hub) sendSnapshot() {
+	if h.Relay == nil {
+		return
+	}
+	env := NewEnvelope(TypePresenceSync, "", "", PresenceSyncPayload{
+		Node:  h.NodeID,
+		Users: h.localPresence(),
+	})
+	if err := h.Relay.Publish(env); err != nil {
+		log.Printf("Error relaying presence: %v", err)
+	}
+}
+
+// updatePresence announces the users whose merged status changed since the
+// last call, and relays the local snapshot when it changed.
+func (h *Hub) updatePresence() {
+	if !h.localChanged && !h.remoteChanged {
+		return
+	}
+
+	merged := h.merged()
+	for nickname, p := range merged {
+		if shown, ok := h.shown[nickname]; !ok || shown.Status != p.Status {
+			h.announce(nickname, p.Status)
+		}
+	}
+	for nickname := range h.shown {
+		if _, ok := merged[nickname]; !ok {
+			h.announce(nickname, PresenceOffline)
+		}
+	}
+	h.shown = merged
+
+	if h.localChanged {
+		h.sendSnapshot()
+	}
+	h.localChanged, h.remoteChanged = false, false
+}
+
+// announce queues a presence event for every client of this node. Events
+// are delivered once the current hub operation is over.
+func (h *Hub) announce(nickname, status string) {
+	h.events = append(h.events, NewEnvelope(TypePresence, "", "", PresencePayload{
+		Nickname: nickname,
+		Status:   status,
+	}))
+}
+
+// flushEvents delivers the presence changes of the last hub operation.
+// Delivering them may drop slow clients, whose departure is announced too.
+func (h *Hub) flushEvents() {
+	for h.updatePresence(); len(h.events) > 0; h.updatePresence() {
+		env := h.events[0]
+		h.events = h.events[1:]
+		h.broadcast(env)
+	}
+}
+
+// idleCheckInterval is how often idle users are looked for.
+func (h *Hub) idleCheckInterval() time.Duration {
+	return min(h.IdleAfter/2, maxIdleCheck)
+}
@@ -0,0 +1,291 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProtocolVersion is the version of the JSON envelope format. Clients opt in
+// to it by requesting the JSONSubprotocol websocket subprotocol; everyone
+// else keeps receiving pre-formatted text lines.
+const (
+	ProtocolVersion = 1
+	JSONSubprotocol = "chat.v1.json"
+)
+
+type Format int
+
+const (
+	FormatText Format = iota
+	FormatJSON
+)
+
+const (
+	TypeChat     = "chat"
+	TypeSystem   = "system"
+	TypeBotReply = "bot_reply"
+	TypeError    = "error"
+	TypeAck      = "ack"
+	// TypeDirect is a one-to-one message. Clients send it with the
+	// recipient in To; the server delivers it with both participants in To.
+	TypeDirect = "dm"
+	// TypePresence tells that a user's status changed and TypeOnline lists
+	// the connected users. Both are only sent by the server.
+	TypePresence = "presence"
+	TypeOnline   = "online"
+	// TypePresenceSync carries the users of a node to the other nodes of a
+	// cluster; clients never receive it.
+	TypePresenceSync = "presence_sync"
+	// TypeTyping tells the other members of a room that the author is
+	// typing. It is never stored and plain-text clients do not receive it.
+	TypeTyping = "typing"
+	// TypeEdit and TypeDelete change the message whose id is in payload.ref;
+	// the server then sends a TypeUpdate with the message id, replacing it.
+	TypeEdit   = "edit"
+	TypeDelete = "delete"
+	TypeUpdate = "update"
+	// TypeReaction adds or removes an emoji reaction on the message whose id
+	// is in payload.ref. The server sends it to the message room with the
+	// reacting user as author and the new count of the emoji.
+	TypeReaction = "reaction"
+)
+
+// Envelope is the unit exchanged over the websocket in both directions.
+// Several envelopes may share one frame, separated by a newline. Envelopes
+// with recipients in To are private and only reach those users.
+type Envelope struct {
+	Version   int             `json:"v"`
+	Type      string          `json:"type"`
+	ID        string          `json:"id,omitempty"`
+	Room      string          `json:"room,omitempty"`
+	Author    string          `json:"author,omitempty"`
+	To        []string        `json:"to,omitempty"`
+	Timestamp time.Time       `json:"ts"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+type TextPayload struct {
+	Text string `json:"text"`
+	// EditedAt and Deleted are only set on chat and update envelopes of
+	// messages that were edited or deleted.
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+	Deleted  bool       `json:"deleted,omitempty"`
+	// Reactions counts the reactions by emoji of the messages replayed
+	// from history.
+	Reactions json.RawMessage `json:"reactions,omitempty"`
+}
+
+// ReactionPayload adds, or removes when Remove is set, the Emoji reaction
+// on the message whose id is Ref. Count is only set by the server.
+type ReactionPayload struct {
+	Ref    string `json:"ref"`
+	Emoji  string `json:"emoji"`
+	Remove bool   `json:"remove,omitempty"`
+	Count  int    `json:"count"`
+}
+
+// EditPayload asks to change the message whose id is Ref; Text is the new
+// content and is ignored by deletes.
+type EditPayload struct {
+	Ref  string `json:"ref"`
+	Text string `json:"text,omitempty"`
+}
+
+// DirectPayload carries a direct message and the id of its channel.
+type DirectPayload struct {
+	Text    string `json:"text"`
+	Channel string `json:"channel,omitempty"`
+}
+
+// BotReplyPayload answers the command whose invocation id is Ref. Quotes
+// holds the quote cards sent by the stock bot, passed through as is. A
+// RetryIn above zero marks a notice that the bot tries again in that many
+// seconds; the final answer still follows.
+type BotReplyPayload struct {
+	Text      string          `json:"text"`
+	Ref       string          `json:"ref,omitempty"`
+	Requester string          `json:"requester,omitempty"`
+	Quotes    json.RawMessage `json:"quotes,omitempty"`
+	RetryIn   int             `json:"retry_in,omitempty"`
+}
+
+// ErrorPayload describes a failure; Ref is set when it answers a command.
+type ErrorPayload struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Ref     string `json:"ref,omitempty"`
+}
+
+// TypingPayload starts (Active) or stops a typing indicator. The server adds
+// TTL, in milliseconds, after which receivers drop an indicator that was not
+// refreshed.
+type TypingPayload struct {
+	Active bool `json:"active"`
+	TTL    int  `json:"ttl,omitempty"`
+}
+
+// AckPayload acknowledges a client envelope; Ref is the id the client sent.
+type AckPayload struct {
+	Ref string `json:"ref"`
+}
+
+// NewEnvelope builds an outgoing envelope with a fresh id.
+func NewEnvelope(envelopeType, room, author string, payload any) Envelope {
+	raw, _ := json.Marshal(payload)
+	return Envelope{
+		Version:   ProtocolVersion,
+		Type:      envelopeType,
+		ID:        uuid.NewString(),
+		Room:      room,
+		Author:    author,
+		Timestamp: time.Now(),
+		Payload:   raw,
+	}
+}
+
+func SystemEnvelope(room, text string) Envelope {
+	return NewEnvelope(TypeSystem, room, "", TextPayload{Text: text})
+}
+
+func ErrorEnvelope(room, code, message string) Envelope {
+	return NewEnvelope(TypeError, room, "", ErrorPayload{Code: code, Message: message})
+}
+
+// DecodeEnvelope parses a frame sent by a client using format. Frames that
+// are not JSON envelopes are treated as chat text so plain-text clients keep
+// working; only clients that negotiated JSONSubprotocol get an error for a
+// malformed envelope.
+func DecodeEnvelope(frame []byte, format Format) (Envelope, error) {
+	if len(frame) == 0 || frame[0] != '{' {
+		return chatText(frame), nil
+	}
+
+	env, err := decodeJSON(frame)
+	if err != nil && format == FormatText {
+		// "{hi" is a chat message from a plain-text client
+		return chatText(frame), nil
+	}
+	return env, err
+}
+
+func chatText(frame []byte) Envelope {
+	return NewEnvelope(TypeChat, "", "", TextPayload{Text: string(frame)})
+}
+
+func decodeJSON(frame []byte) (Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(frame, &env); err != nil {
+		return Envelope{}, fmt.Errorf("malformed envelope: %w", err)
+	}
+	if env.Version != 0 && env.Version != ProtocolVersion {
+		return Envelope{}, fmt.Errorf("unsupported protocol version %d", env.Version)
+	}
+	if env.Type == "" {
+		return Envelope{}, fmt.Errorf("missing envelope type")
+	}
+
+	return env, nil
+}
+
+// Text returns the text carried by chat, system and bot reply payloads.
+func (e Envelope) Text() string {
+	var payload TextPayload
+	_ = json.Unmarshal(e.Payload, &payload)
+	return payload.Text
+}
+
+// Ref returns the id of the envelope this one answers, if any.
+func (e Envelope) Ref() string {
+	var payload struct {
+		Ref string `json:"ref"`
+	}
+	_ = json.Unmarshal(e.Payload, &payload)
+	return payload.Ref
+}
+
+// Recipient returns the participant of a direct message who is not its
+// author.
+func (e Envelope) Recipient() string {
+	for _, nickname := range e.To {
+		if nickname != e.Author {
+			return nickname
+		}
+	}
+	return ""
+}
+
+// Encode renders the envelope for the given format. Envelopes that have no
+// text representation return nil.
+func (e Envelope) Encode(format Format) []byte {
+	if format == FormatJSON {
+		frame, _ := json.Marshal(e)
+		return frame
+	}
+
+	timestamp := e.Timestamp.Format("15:04:05")
+	switch e.Type {
+	case TypeChat:
+		var payload TextPayload
+		_ = json.Unmarshal(e.Payload, &payload)
+		switch {
+		case payload.Deleted:
+			return []byte(fmt.Sprintf("[%s] %s: (message deleted)", timestamp, e.Author))
+		case payload.EditedAt != nil:
+			return []byte(fmt.Sprintf("[%s] %s: %s (edited)", timestamp, e.Author, payload.Text))
+		default:
+			return []byte(fmt.Sprintf("[%s] %s: %s", timestamp, e.Author, payload.Text))
+		}
+	case TypeUpdate:
+		// plain-text lines cannot be replaced, so the change is told instead
+		var payload TextPayload
+		_ = json.Unmarshal(e.Payload, &payload)
+		if payload.Deleted {
+			return []byte(fmt.Sprintf("[%s] A message of %s was deleted", timestamp, e.Author))
+		}
+		return []byte(fmt.Sprintf("[%s] %s edited a message: %s", timestamp, e.Author, payload.Text))
+	case TypeSystem:
+		return []byte(fmt.Sprintf("[%s] %s", timestamp, e.Text()))
+	case TypeDirect:
+		return []byte(fmt.Sprintf("[%s] (DM) %s -> %s: %s", timestamp, e.Author, e.Recipient(), e.Text()))
+	case TypeBotReply:
+		var payload BotReplyPayload
+		_ = json.Unmarshal(e.Payload, &payload)
+		if payload.Requester == "" {
+			return []byte(fmt.Sprintf("[%s] %s", timestamp, payload.Text))
+		}
+		return []byte(fmt.Sprintf("[%s] @%s %s", timestamp, payload.Requester, payload.Text))
+	case TypeError:
+		var payload ErrorPayload
+		_ = json.Unmarshal(e.Payload, &payload)
+		return []byte(fmt.Sprintf("[%s] Error: %s", timestamp, payload.Message))
+	case TypePresence:
+		var payload PresencePayload
+		_ = json.Unmarshal(e.Payload, &payload)
+		// idle changes are too chatty for plain-text clients
+		switch payload.Status {
+		case PresenceOnline:
+			return []byte(fmt.Sprintf("[%s] %s is online", timestamp, payload.Nickname))
+		case PresenceOffline:
+			return []byte(fmt.Sprintf("[%s] %s left", timestamp, payload.Nickname))
+		default:
+			return nil
+		}
+	case TypeOnline:
+		var payload OnlinePayload
+		_ = json.Unmarshal(e.Payload, &payload)
+		users := make([]string, len(payload.Users))
+		for i, user := range payload.Users {
+			users[i] = user.Nickname
+			if user.Status == PresenceIdle {
+				users[i] += " (idle)"
+			}
+		}
+		return []byte(fmt.Sprintf("[%s] Online: %s", timestamp, strings.Join(users, ", ")))
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,45 @@
+package websocket_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ws "github.com/LuccChagas/my-chat-app/internal/websocket"
+)
+
+func TestDecodeEnvelope(t *testing.T) {
+	tests := []struct {
+		name     string
+		frame    string
+		format   ws.Format
+		wantType string
+		wantText string
+		wantErr  string
+	}{
+		{name: "text", frame: "hello", format: ws.FormatText, wantType: ws.TypeChat, wantText: "hello"},
+		{name: "text from a JSON client", frame: "hello", format: ws.FormatJSON, wantType: ws.TypeChat, wantText: "hello"},
+		{name: "empty", frame: "", format: ws.FormatText, wantType: ws.TypeChat},
+		{name: "envelope", frame: `{"v":1,"type":"chat","payload":{"text":"hi"}}`, format: ws.FormatJSON, wantType: ws.TypeChat, wantText: "hi"},
+		{name: "envelope from a plain-text client", frame: `{"type":"chat","payload":{"text":"hi"}}`, format: ws.FormatText, wantType: ws.TypeChat, wantText: "hi"},
+		{name: "brace text", frame: "{hi", format: ws.FormatText, wantType: ws.TypeChat, wantText: "{hi"},
+		{name: "empty object text", frame: "{}", format: ws.FormatText, wantType: ws.TypeChat, wantText: "{}"},
+		{name: "other version text", frame: `{"v":9,"type":"chat"}`, format: ws.FormatText, wantType: ws.TypeChat, wantText: `{"v":9,"type":"chat"}`},
+		{name: "malformed envelope", frame: "{hi", format: ws.FormatJSON, wantErr: "malformed envelope"},
+		{name: "missing type", frame: "{}", format: ws.FormatJSON, wantErr: "missing envelope type"},
+		{name: "unsupported version", frame: `{"v":9,"type":"chat"}`, format: ws.FormatJSON, wantErr: "unsupported protocol version 9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, err := ws.DecodeEnvelope([]byte(tt.frame), tt.format)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantType, env.Type)
+			assert.Equal(t, tt.wantText, env.Text())
+		})
+	}
+}
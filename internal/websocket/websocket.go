@@ -1,11 +1,19 @@
 package websocket
 
 import (
-	"github.com/LuccChagas/my-chat-app/internal/models"
+	"github.com/labstack/gommon/log"
 	"io"
+	"regexp"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const DefaultRoom = "general"
+
+var roomPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
 type WSConn interface {
 	ReadMessage() (int, []byte, error)
 	SetReadLimit(limit int64)
@@ -17,51 +25,265 @@ type WSConn interface {
 	WriteMessage(messageType int, data []byte) error
 }
 
+// Relay shares local broadcasts with the other server instances.
+type Relay interface {
+	Publish(env Envelope) error
+}
+
+// Subscription moves a registered client into another room.
+type Subscription struct {
+	Client *Client
+	Room   string
+}
+
 type Hub struct {
-	Clients    map[*Client]bool
-	Broadcast  chan []byte
+	// Clients maps every registered client to the room it is currently in.
+	Clients map[*Client]string
+	Rooms   map[string]map[*Client]bool
+	// Broadcast delivers an envelope to its recipients when it has any,
+	// otherwise to every member of its room, or to every connected client
+	// when the room is empty. Typing envelopes skip their author.
+	Broadcast chan Envelope
+	// Remote receives broadcasts relayed by other nodes; they are only
+	// delivered locally.
+	Remote     chan Envelope
 	Register   chan *Client
 	Unregister chan *Client
-	Messages   []models.Message
+	Join       chan Subscription
+	// Relay is optional and must be set before Run is started.
+	Relay Relay
+	// Observer is optional, must be set before Run is started and is called
+	// from Run for every envelope delivered by this node, relayed ones
+	// included. It must not block.
+	Observer func(env Envelope)
+	// IdleAfter is how long the tabs of a user must be silent before the
+	// user is shown as idle; zero disables idle tracking. It must be set
+	// before Run is started.
+	IdleAfter time.Duration
+	// NodeID names this node in the presence snapshots it relays; it must be
+	// set with Relay.
+	NodeID string
+
+	// presence merges the tabs of the users connected to this node and
+	// remote holds the snapshots of the other nodes; shown is the merged
+	// view last announced. They are only touched by Run.
+	presence        map[string]*Presence
+	remote          map[string]*nodePresence
+	shown           map[string]Presence
+	localChanged    bool
+	remoteChanged   bool
+	presenceQueries chan chan []Presence
+	events          []Envelope
 }
 
 type Client struct {
-	Hub      *Hub
-	Conn     WSConn
+	Hub  *Hub
+	Conn WSConn
+	// Send is written by the hub and by the client's reading goroutine, and
+	// only through SendEnvelope, Close and the hub, which never queue a
+	// frame once it is closed.
 	Send     chan []byte
 	Nickname string
+	// Room is only written by the client's reading goroutine.
+	Room   string
+	Format Format
+
+	sendMu sync.Mutex
+	closed bool
+
+	lastActive atomic.Int64
 }
 
 func NewHub() *Hub {
 	return &Hub{
-		Broadcast:  make(chan []byte),
+		Broadcast:  make(chan Envelope),
+		Remote:     make(chan Envelope),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
-		Clients:    make(map[*Client]bool),
+		Join:       make(chan Subscription),
+		Clients:    make(map[*Client]string),
+		Rooms:      make(map[string]map[*Client]bool),
+		IdleAfter:  DefaultIdleAfter,
+
+		presence:        make(map[string]*Presence),
+		presenceQueries: make(chan chan []Presence),
 	}
 }
 
+// ValidRoom reports whether name can be used as a room name.
+func ValidRoom(name string) bool {
+	return roomPattern.MatchString(name)
+}
+
 func (h *Hub) Run() {
+	var idleTicks <-chan time.Time
+	if h.IdleAfter > 0 {
+		ticker := time.NewTicker(h.idleCheckInterval())
+		defer ticker.Stop()
+		idleTicks = ticker.C
+	}
+	var syncTicks <-chan time.Time
+	if h.Relay != nil {
+		ticker := time.NewTicker(presenceSyncInterval)
+		defer ticker.Stop()
+		syncTicks = ticker.C
+		h.sendSnapshot()
+	}
+
 	for {
 		select {
 		case client := <-h.Register:
-			h.Clients[client] = true
+			h.Clients[client] = client.Room
+			h.addMember(client.Room, client)
+			h.arrive(client)
+			h.deliver(client, NewEnvelope(TypeOnline, client.Room, "", OnlinePayload{Users: h.online()}), nil)
 
 		case client := <-h.Unregister:
-			if _, ok := h.Clients[client]; ok {
+			if room, ok := h.Clients[client]; ok {
+				h.removeMember(room, client)
 				delete(h.Clients, client)
-				close(client.Send)
+				client.Close()
+				h.depart(client)
 			}
 
-		case message := <-h.Broadcast:
-			for client := range h.Clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(h.Clients, client)
+		case sub := <-h.Join:
+			if room, ok := h.Clients[sub.Client]; ok {
+				h.removeMember(room, sub.Client)
+				h.Clients[sub.Client] = sub.Room
+				h.addMember(sub.Room, sub.Client)
+			}
+
+		case env := <-h.Broadcast:
+			h.broadcast(env)
+			if h.Relay != nil {
+				if err := h.Relay.Publish(env); err != nil {
+					log.Printf("Error relaying broadcast: %v", err)
 				}
 			}
+
+		case env := <-h.Remote:
+			if env.Type == TypePresenceSync {
+				h.syncRemote(env, time.Now())
+				break
+			}
+			h.broadcast(env)
+
+		case reply := <-h.presenceQueries:
+			reply <- h.online()
+
+		case now := <-idleTicks:
+			h.checkIdle(now)
+
+		case now := <-syncTicks:
+			h.expireRemote(now)
+			h.sendSnapshot()
+		}
+
+		h.flushEvents()
+	}
+}
+
+func (h *Hub) broadcast(env Envelope) {
+	if h.Observer != nil {
+		h.Observer(env)
+	}
+
+	frames := make(map[Format][]byte)
+	if len(env.To) > 0 {
+		for client := range h.Clients {
+			if slices.Contains(env.To, client.Nickname) {
+				h.deliver(client, env, frames)
+			}
 		}
+		return
+	}
+	if env.Room == "" {
+		for client := range h.Clients {
+			h.deliver(client, env, frames)
+		}
+		return
+	}
+	for client := range h.Rooms[env.Room] {
+		if env.Type == TypeTyping && client.Nickname == env.Author {
+			continue
+		}
+		h.deliver(client, env, frames)
+	}
+}
+
+// SendEnvelope queues an envelope for this client only. Like broadcasts, it
+// never blocks: a client too slow to empty its buffer is closed.
+func (c *Client) SendEnvelope(env Envelope) {
+	if frame := env.Encode(c.Format); frame != nil {
+		c.queue(frame)
+	}
+}
+
+// Close closes Send, which makes the writer close the connection. It can be
+// called several times.
+func (c *Client) Close() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.Send)
+	}
+}
+
+// queue adds frame to Send and reports whether it did. When the buffer is
+// full the client is closed instead.
+func (c *Client) queue(frame []byte) bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.closed {
+		return false
+	}
+	select {
+	case c.Send <- frame:
+		return true
+	default:
+		c.closed = true
+		close(c.Send)
+		return false
+	}
+}
+
+// deliver encodes env once per format and queues it for client. frames
+// caches the encodings when env goes to several clients and may be nil.
+func (h *Hub) deliver(client *Client, env Envelope, frames map[Format][]byte) {
+	frame, ok := frames[client.Format]
+	if !ok {
+		frame = env.Encode(client.Format)
+		if frames != nil {
+			frames[client.Format] = frame
+		}
+	}
+	if frame == nil {
+		return
+	}
+
+	if !client.queue(frame) {
+		h.removeMember(h.Clients[client], client)
+		delete(h.Clients, client)
+		h.depart(client)
+	}
+}
+
+func (h *Hub) addMember(room string, client *Client) {
+	if h.Rooms == nil {
+		h.Rooms = make(map[string]map[*Client]bool)
+	}
+	if h.Rooms[room] == nil {
+		h.Rooms[room] = make(map[*Client]bool)
+	}
+	h.Rooms[room][client] = true
+}
+
+func (h *Hub) removeMember(room string, client *Client) {
+	delete(h.Rooms[room], client)
+	if len(h.Rooms[room]) == 0 {
+		delete(h.Rooms, room)
 	}
 }
@@ -0,0 +1,164 @@
+package websocket_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	ws "github.com/LuccChagas/my-chat-app/internal/websocket"
+)
+
+func TestSendEnvelope_FullBufferClosesClient(t *testing.T) {
+	client := newTestClient(nil, "alice", 1)
+
+	client.SendEnvelope(ws.SystemEnvelope(ws.DefaultRoom, "first"))
+	client.SendEnvelope(ws.SystemEnvelope(ws.DefaultRoom, "second"))
+	// the client is closed, later sends and closes are dropped
+	assert.NotPanics(t, func() {
+		client.SendEnvelope(ws.SystemEnvelope(ws.DefaultRoom, "third"))
+		client.Close()
+	})
+
+	frames, closed := drain(client)
+	assert.Len(t, frames, 1)
+	assert.True(t, closed)
+}
+
+func TestHub_ReaderAndHubShareAFullBuffer(t *testing.T) {
+	hub := newTestHub()
+	// the online list fills the buffer as soon as the client registers
+	client := newTestClient(hub, "alice", 1)
+	hub.Register <- client
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for range 50 {
+			client.SendEnvelope(ws.SystemEnvelope(ws.DefaultRoom, "ack"))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 50 {
+			hub.Broadcast <- ws.SystemEnvelope(ws.DefaultRoom, "broadcast")
+		}
+	}()
+
+	assert.NotPanics(t, wg.Wait)
+	hub.Unregister <- client
+	hub.Online() // waits for the unregister to be handled
+
+	_, closed := drain(client)
+	assert.True(t, closed)
+}
+
+func TestHub_PresenceMergesTabs(t *testing.T) {
+	hub := newTestHub()
+	watcher := newTestClient(hub, "carol", 16)
+	hub.Register <- watcher
+	firstTab := newTestClient(hub, "alice", 16)
+	secondTab := newTestClient(hub, "alice", 16)
+
+	hub.Register <- firstTab
+	hub.Register <- secondTab
+	assert.Equal(t, map[string]int{"alice": 2, "carol": 1}, onlineTabs(hub))
+	assert.Equal(t, []string{"carol online", "alice online"}, presenceEvents(t, watcher),
+		"A second tab must not announce the user again")
+
+	hub.Unregister <- firstTab
+	assert.Equal(t, map[string]int{"alice": 1, "carol": 1}, onlineTabs(hub))
+	assert.Empty(t, presenceEvents(t, watcher), "The user is still online in another tab")
+
+	hub.Unregister <- secondTab
+	assert.Equal(t, map[string]int{"carol": 1}, onlineTabs(hub))
+	assert.Equal(t, []string{"alice offline"}, presenceEvents(t, watcher))
+}
+
+func TestHub_PresenceIdleTransitions(t *testing.T) {
+	hub := ws.NewHub()
+	hub.IdleAfter = 40 * time.Millisecond
+	go hub.Run()
+
+	client := newTestClient(hub, "alice", 16)
+	hub.Register <- client
+	hub.Online() // waits for the register to be handled
+	assert.Equal(t, []string{"alice online"}, presenceEvents(t, client))
+
+	var events []string
+	assert.Eventually(t, func() bool {
+		events = append(events, presenceEvents(t, client)...)
+		return len(events) > 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"alice idle"}, events)
+	assert.Equal(t, ws.PresenceIdle, hub.Online()[0].Status)
+
+	client.Touch()
+	events = nil
+	assert.Eventually(t, func() bool {
+		events = append(events, presenceEvents(t, client)...)
+		return len(events) > 0
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"alice online"}, events)
+
+	watcher := newTestClient(hub, "carol", 16)
+	hub.Register <- watcher
+	hub.Unregister <- client
+	hub.Online() // waits for the unregister to be handled
+	assert.Contains(t, presenceEvents(t, watcher), "alice offline")
+}
+
+func TestHub_PresenceAcrossNodes(t *testing.T) {
+	relay := new(FakeRelay)
+	relay.On("Publish", mock.Anything).Return(nil)
+	hub := ws.NewHub()
+	hub.IdleAfter = 0
+	hub.Relay = relay
+	hub.NodeID = "node-a"
+	go hub.Run()
+
+	watcher := newTestClient(hub, "carol", 16)
+	hub.Register <- watcher
+	hub.Online()
+	assert.Equal(t, []string{"carol online"}, presenceEvents(t, watcher))
+
+	since := time.Now()
+	hub.Remote <- presenceSync("node-b", ws.Presence{Nickname: "bob", Status: ws.PresenceOnline, Since: since, Tabs: 1})
+	assert.Equal(t, map[string]int{"bob": 1, "carol": 1}, onlineTabs(hub))
+	assert.Equal(t, []string{"bob online"}, presenceEvents(t, watcher))
+
+	// a new node is answered with this node's users right away
+	snapshots := relay.snapshots(t)
+	if assert.NotEmpty(t, snapshots) {
+		last := snapshots[len(snapshots)-1]
+		assert.Equal(t, "node-a", last.Node)
+		if assert.Len(t, last.Users, 1) {
+			assert.Equal(t, "carol", last.Users[0].Nickname)
+		}
+	}
+
+	// echoes of this node's own snapshots are ignored
+	hub.Remote <- presenceSync("node-a", ws.Presence{Nickname: "mallory", Status: ws.PresenceOnline, Tabs: 1})
+	assert.NotContains(t, onlineTabs(hub), "mallory")
+
+	// bob opens a tab on this node too, the other node sees him idle
+	bobTab := newTestClient(hub, "bob", 16)
+	hub.Register <- bobTab
+	hub.Remote <- presenceSync("node-b", ws.Presence{Nickname: "bob", Status: ws.PresenceIdle, Since: since, Tabs: 1})
+	online := hub.Online()
+	assert.Equal(t, map[string]int{"bob": 2, "carol": 1}, onlineTabs(hub))
+	assert.Equal(t, ws.PresenceOnline, online[0].Status, "Bob is active on this node")
+	assert.Empty(t, presenceEvents(t, watcher))
+
+	// bob leaves the other node, then this one
+	hub.Remote <- presenceSync("node-b")
+	assert.Equal(t, map[string]int{"bob": 1, "carol": 1}, onlineTabs(hub))
+	assert.Empty(t, presenceEvents(t, watcher))
+
+	hub.Unregister <- bobTab
+	assert.Equal(t, map[string]int{"carol": 1}, onlineTabs(hub))
+	assert.Equal(t, []string{"bob offline"}, presenceEvents(t, watcher))
+}
@@ -0,0 +1,159 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"time"
+)
+
+// Kind tells how a topic delivers its messages.
+type Kind int
+
+const (
+	// Queue delivers every message to exactly one subscriber and keeps it
+	// until someone subscribes.
+	Queue Kind = iota
+	// Fanout delivers every message to all current subscribers.
+	Fanout
+)
+
+var ErrClosed = errors.New("bus is closed")
+
+const (
+	// AttemptHeader counts the deliveries of a message, starting at 1.
+	AttemptHeader = "x-attempt"
+	// ErrorHeader keeps the last handler error of a dead-lettered message.
+	ErrorHeader = "x-error"
+)
+
+type Message struct {
+	ContentType string
+	// CorrelationID ties a reply to its request and ReplyTo names the topic
+	// the reply should be published on.
+	CorrelationID string
+	ReplyTo       string
+	Headers       map[string]string
+	Body          []byte
+}
+
+// Handler processes one message. A message is only acknowledged once its
+// handler returns nil; on error it is retried according to the topic's
+// RetryPolicy, or dropped when the topic has none.
+type Handler func(ctx context.Context, msg Message) error
+
+// Bus is a publish/subscribe transport addressed by topic. Topics are Queue
+// topics unless declared otherwise.
+type Bus interface {
+	Declare(topic string, kind Kind) error
+	// SetRetryPolicy enables retries and dead-lettering on a Queue topic. It
+	// must be called before subscribing to the topic.
+	SetRetryPolicy(topic string, policy RetryPolicy) error
+	// SetConcurrency sets how many messages of topic are handled at once by
+	// each subscriber, one by default. It must be called before subscribing
+	// to the topic.
+	SetConcurrency(topic string, workers int) error
+	Publish(ctx context.Context, topic string, msg Message) error
+	Subscribe(topic string, handler Handler) error
+	// Status reports whether the bus can currently deliver messages.
+	Status() Status
+	// Shutdown stops the consumers, waits for the messages being handled to
+	// be settled and closes the bus. When ctx is done first, the handlers are
+	// canceled and their messages redelivered later where supported.
+	Shutdown(ctx context.Context) error
+	Close() error
+}
+
+// Status describes the health of a bus backend.
+type Status struct {
+	Connected  bool      `json:"connected"`
+	Since      time.Time `json:"since"`
+	Reconnects int       `json:"reconnects"`
+	LastError  string    `json:"last_error,omitempty"`
+	// Consumers counts the subscriptions currently receiving messages.
+	Consumers int `json:"consumers"`
+}
+
+// DeadLetters is implemented by the buses that let the dead-lettered
+// messages of a topic be inspected and published again.
+type DeadLetters interface {
+	// DeadLetters returns up to limit messages without removing them.
+	DeadLetters(ctx context.Context, topic string, limit int) ([]Message, error)
+	// Replay moves up to limit messages back to topic and returns how many
+	// were moved.
+	Replay(ctx context.Context, topic string, limit int) (int, error)
+}
+
+// RetryPolicy redelivers a failed message after Backoff, doubling the delay
+// on every attempt, and dead-letters it once MaxAttempts deliveries failed.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+// Delay returns how long to wait after the given failed attempt.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	return p.Backoff << (attempt - 1)
+}
+
+func validateWorkers(workers int) error {
+	if workers < 1 {
+		return fmt.Errorf("invalid concurrency: %d workers", workers)
+	}
+	return nil
+}
+
+func (p RetryPolicy) validate() error {
+	if p.MaxAttempts < 1 || p.Backoff <= 0 {
+		return fmt.Errorf("invalid retry policy: %d attempts, %s backoff", p.MaxAttempts, p.Backoff)
+	}
+	return nil
+}
+
+// DeadLetterTopic names the topic holding the messages of topic that
+// exhausted their attempts.
+func DeadLetterTopic(topic string) string {
+	return topic + ".dlq"
+}
+
+// Attempt returns which delivery of msg this is, starting at 1.
+func Attempt(msg Message) int {
+	attempt, err := strconv.Atoi(msg.Headers[AttemptHeader])
+	if err != nil || attempt < 1 {
+		return 1
+	}
+	return attempt
+}
+
+// withHeader returns a copy of msg with the header set, or removed when
+// value is empty.
+func withHeader(msg Message, key, value string) Message {
+	headers := maps.Clone(msg.Headers)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	if value == "" {
+		delete(headers, key)
+	} else {
+		headers[key] = value
+	}
+	msg.Headers = headers
+	return msg
+}
+
+// retried prepares msg for the delivery after attempt.
+func retried(msg Message, attempt int) Message {
+	return withHeader(msg, AttemptHeader, strconv.Itoa(attempt+1))
+}
+
+// deadLettered records why msg is dead-lettered.
+func deadLettered(msg Message, err error) Message {
+	return withHeader(msg, ErrorHeader, err.Error())
+}
+
+// replayed clears the retry state of a dead-lettered msg.
+func replayed(msg Message) Message {
+	return withHeader(withHeader(msg, AttemptHeader, ""), ErrorHeader, "")
+}
@@ -0,0 +1,165 @@
+package bus
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// ErrDisconnected is returned while the broker connection is being restored.
+var ErrDisconnected = errors.New("message broker is disconnected")
+
+// RabbitConnection keeps an AMQP connection open. When the broker closes it,
+// it redials with an exponential backoff and then calls the OnReconnect
+// callbacks so the topology and the consumers can be restored.
+type RabbitConnection struct {
+	url  string
+	done chan struct{}
+
+	mu          sync.Mutex
+	conn        *amqp091.Connection
+	status      Status
+	onReconnect []func()
+	closed      bool
+}
+
+// DialRabbit opens the first connection, which must succeed.
+func DialRabbit(url string) (*RabbitConnection, error) {
+	c := &RabbitConnection{
+		url:  url,
+		done: make(chan struct{}),
+	}
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// OnReconnect registers fn to run after every successful reconnection.
+func (c *RabbitConnection) OnReconnect(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.onReconnect = append(c.onReconnect, fn)
+}
+
+// Channel opens a channel, or fails with ErrDisconnected during an outage.
+func (c *RabbitConnection) Channel() (*amqp091.Channel, error) {
+	c.mu.Lock()
+	conn, connected := c.conn, c.status.Connected
+	c.mu.Unlock()
+
+	if c.isClosed() {
+		return nil, ErrClosed
+	}
+	if !connected || conn.IsClosed() {
+		return nil, ErrDisconnected
+	}
+	return conn.Channel()
+}
+
+func (c *RabbitConnection) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.status
+}
+
+func (c *RabbitConnection) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	conn := c.conn
+	c.status.Connected = false
+	c.mu.Unlock()
+
+	if conn.IsClosed() {
+		return nil
+	}
+	return conn.Close()
+}
+
+func (c *RabbitConnection) connect() error {
+	conn, err := amqp091.Dial(c.url)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	c.status.Connected = true
+	c.status.Since = time.Now()
+	c.mu.Unlock()
+
+	go c.watch(conn.NotifyClose(make(chan *amqp091.Error, 1)))
+	return nil
+}
+
+// watch waits for the connection to drop and redials until it succeeds or
+// the connection is closed on purpose.
+func (c *RabbitConnection) watch(closes chan *amqp091.Error) {
+	amqpErr := <-closes
+	if c.isClosed() {
+		return
+	}
+
+	c.mu.Lock()
+	c.status.Connected = false
+	c.status.Since = time.Now()
+	if amqpErr != nil {
+		c.status.LastError = amqpErr.Error()
+	}
+	c.mu.Unlock()
+	log.Printf("RabbitMQ connection lost: %v", amqpErr)
+
+	for delay := minReconnectDelay; ; delay = min(2*delay, maxReconnectDelay) {
+		select {
+		case <-time.After(delay):
+		case <-c.done:
+			return
+		}
+
+		if err := c.connect(); err != nil {
+			log.Printf("Error reconnecting to RabbitMQ, retrying in %s: %v", min(2*delay, maxReconnectDelay), err)
+			c.mu.Lock()
+			c.status.LastError = err.Error()
+			c.mu.Unlock()
+			continue
+		}
+
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			c.conn.Close()
+			return
+		}
+		c.status.Reconnects++
+		callbacks := append([]func(){}, c.onReconnect...)
+		c.mu.Unlock()
+
+		log.Printf("RabbitMQ connection restored")
+		for _, fn := range callbacks {
+			fn()
+		}
+		return
+	}
+}
+
+func (c *RabbitConnection) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closed
+}
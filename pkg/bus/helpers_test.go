@@ -0,0 +1,67 @@
+package bus_test
+
+import (
+	"context"
+	"sync"
+
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+)
+
+// blockingHandler holds every message until release is closed and records
+// how many run at once.
+type blockingHandler struct {
+	release chan struct{}
+
+	mu        sync.Mutex
+	active    int
+	maxActive int
+	acked     int
+	canceled  int
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{release: make(chan struct{})}
+}
+
+func (h *blockingHandler) handle(ctx context.Context, msg bus.Message) error {
+	h.mu.Lock()
+	h.active++
+	h.maxActive = max(h.maxActive, h.active)
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		h.active--
+		h.mu.Unlock()
+	}()
+
+	select {
+	case <-h.release:
+		h.mu.Lock()
+		h.acked++
+		h.mu.Unlock()
+		return nil
+	case <-ctx.Done():
+		h.mu.Lock()
+		h.canceled++
+		h.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// counts returns the handlers running now, the most that ran at once, and
+// how many messages were acked and canceled.
+func (h *blockingHandler) counts() (active, maxActive, acked, canceled int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.active, h.maxActive, h.acked, h.canceled
+}
+
+func publishN(b bus.Bus, topic string, n int) error {
+	for range n {
+		if err := b.Publish(context.Background(), topic, bus.Message{Body: []byte("job")}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
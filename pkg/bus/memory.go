@@ -0,0 +1,280 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
+
+const memoryBufferSize = 256
+
+// MemoryBus is an in-process Bus for single binary deployments and tests.
+// Retries are scheduled with timers and dead letters are kept in memory, so
+// both are lost when the process exits, as are the messages still queued
+// in memory at shutdown.
+type MemoryBus struct {
+	mu       sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stop     chan struct{}
+	running  sync.WaitGroup
+	kinds    map[string]Kind
+	policies map[string]RetryPolicy
+	workers  map[string]int
+	subs     map[string][]chan Message
+	next     map[string]int
+	pending  map[string][]Message
+	dead     map[string][]Message
+	closed   bool
+}
+
+func NewMemoryBus() *MemoryBus {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &MemoryBus{
+		ctx:      ctx,
+		cancel:   cancel,
+		stop:     make(chan struct{}),
+		kinds:    make(map[string]Kind),
+		policies: make(map[string]RetryPolicy),
+		workers:  make(map[string]int),
+		subs:     make(map[string][]chan Message),
+		next:     make(map[string]int),
+		pending:  make(map[string][]Message),
+		dead:     make(map[string][]Message),
+	}
+}
+
+func (b *MemoryBus) Declare(topic string, kind Kind) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.kinds[topic] = kind
+	return nil
+}
+
+func (b *MemoryBus) SetRetryPolicy(topic string, policy RetryPolicy) error {
+	if err := policy.validate(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.kinds[topic] == Fanout {
+		return fmt.Errorf("retries are only supported on queue topics, %s is a fanout", topic)
+	}
+	b.policies[topic] = policy
+	return nil
+}
+
+func (b *MemoryBus) SetConcurrency(topic string, workers int) error {
+	if err := validateWorkers(workers); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.workers[topic] = workers
+	return nil
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, topic string, msg Message) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+
+	subs := b.subs[topic]
+	var targets []chan Message
+	switch {
+	case b.kinds[topic] == Fanout:
+		targets = subs
+	case len(subs) == 0:
+		// queue topics keep messages until the first subscriber shows up
+		b.pending[topic] = append(b.pending[topic], msg)
+	default:
+		i := b.next[topic] % len(subs)
+		b.next[topic] = i + 1
+		targets = subs[i : i+1]
+	}
+	b.mu.Unlock()
+
+	for _, target := range targets {
+		select {
+		case target <- msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-b.ctx.Done():
+			return ErrClosed
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(topic string, handler Handler) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+
+	inbox := make(chan Message, memoryBufferSize)
+	b.subs[topic] = append(b.subs[topic], inbox)
+
+	pending := b.pending[topic]
+	delete(b.pending, topic)
+
+	work := func(pending []Message) {
+		defer b.running.Done()
+		for _, msg := range pending {
+			b.handle(topic, handler, msg)
+		}
+		for {
+			select {
+			case msg := <-inbox:
+				b.handle(topic, handler, msg)
+			case <-b.stop:
+				return
+			}
+		}
+	}
+
+	workers := max(b.workers[topic], 1)
+	b.running.Add(workers)
+	// the first worker catches up with what was published before
+	go work(pending)
+	for range workers - 1 {
+		go work(nil)
+	}
+
+	return nil
+}
+
+func (b *MemoryBus) handle(topic string, handler Handler, msg Message) {
+	err := handler(b.ctx, msg)
+	if err == nil {
+		return
+	}
+	log.Printf("Error handling message from %s: %v", topic, err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	policy, ok := b.policies[topic]
+	if !ok {
+		return
+	}
+
+	attempt := Attempt(msg)
+	if attempt >= policy.MaxAttempts {
+		dlq := DeadLetterTopic(topic)
+		b.dead[dlq] = append(b.dead[dlq], deadLettered(msg, err))
+		return
+	}
+
+	next := retried(msg, attempt)
+	time.AfterFunc(policy.Delay(attempt), func() {
+		if err := b.Publish(b.ctx, topic, next); err != nil {
+			log.Printf("Error retrying message on %s: %v", topic, err)
+		}
+	})
+}
+
+func (b *MemoryBus) DeadLetters(ctx context.Context, topic string, limit int) ([]Message, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	dead := b.dead[DeadLetterTopic(topic)]
+	if limit < len(dead) {
+		dead = dead[:limit]
+	}
+	return append([]Message(nil), dead...), nil
+}
+
+func (b *MemoryBus) Replay(ctx context.Context, topic string, limit int) (int, error) {
+	dlq := DeadLetterTopic(topic)
+
+	b.mu.Lock()
+	dead := b.dead[dlq]
+	if limit < len(dead) {
+		dead = dead[:limit]
+	}
+	b.dead[dlq] = b.dead[dlq][len(dead):]
+	b.mu.Unlock()
+
+	for i, msg := range dead {
+		if err := b.Publish(ctx, topic, replayed(msg)); err != nil {
+			// put back what could not be replayed
+			b.mu.Lock()
+			b.dead[dlq] = append(append([]Message(nil), dead[i:]...), b.dead[dlq]...)
+			b.mu.Unlock()
+			return i, err
+		}
+	}
+	return len(dead), nil
+}
+
+// Status reports the bus as connected until it is closed.
+func (b *MemoryBus) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := Status{Connected: !b.closed}
+	select {
+	case <-b.stop:
+	default:
+		for _, subs := range b.subs {
+			status.Consumers += len(subs)
+		}
+	}
+	return status
+}
+
+// Shutdown stops the workers once their current message is handled and
+// closes the bus, canceling the handlers still running when ctx is done.
+func (b *MemoryBus) Shutdown(ctx context.Context) error {
+	b.stopWorkers()
+
+	drained := make(chan struct{})
+	go func() {
+		b.running.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = fmt.Errorf("draining the subscribers: %w", ctx.Err())
+	}
+	return errors.Join(err, b.Close())
+}
+
+func (b *MemoryBus) Close() error {
+	b.stopWorkers()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	b.cancel()
+	return nil
+}
+
+func (b *MemoryBus) stopWorkers() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	select {
+	case <-b.stop:
+	default:
+		close(b.stop)
+	}
+}
@@ -0,0 +1,108 @@
+package bus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/LuccChagas/my-chat-app/pkg/bus"
+)
+
+func TestMemoryBus_ConcurrencyLimit(t *testing.T) {
+	b := bus.NewMemoryBus()
+	defer b.Close()
+	handler := newBlockingHandler()
+
+	require.NoError(t, b.SetConcurrency("jobs", 3))
+	require.NoError(t, b.Subscribe("jobs", handler.handle))
+	require.NoError(t, publishN(b, "jobs", 10))
+
+	assert.Eventually(t, func() bool {
+		active, _, _, _ := handler.counts()
+		return active == 3
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	_, maxActive, _, _ := handler.counts()
+	assert.Equal(t, 3, maxActive, "No more messages than workers are handled at once")
+
+	close(handler.release)
+	assert.Eventually(t, func() bool {
+		_, _, acked, _ := handler.counts()
+		return acked == 10
+	}, time.Second, 5*time.Millisecond)
+	_, maxActive, _, _ = handler.counts()
+	assert.Equal(t, 3, maxActive)
+}
+
+func TestMemoryBus_InvalidConcurrency(t *testing.T) {
+	b := bus.NewMemoryBus()
+	defer b.Close()
+
+	assert.Error(t, b.SetConcurrency("jobs", 0))
+}
+
+func TestMemoryBus_ShutdownWaitsForInFlight(t *testing.T) {
+	b := bus.NewMemoryBus()
+	handler := newBlockingHandler()
+
+	require.NoError(t, b.SetConcurrency("jobs", 2))
+	require.NoError(t, b.Subscribe("jobs", handler.handle))
+	require.NoError(t, publishN(b, "jobs", 2))
+	assert.Eventually(t, func() bool {
+		active, _, _, _ := handler.counts()
+		return active == 2
+	}, time.Second, 5*time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		done <- b.Shutdown(ctx)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Shutdown returned while messages were being handled")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(handler.release)
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown did not return once the handlers were done")
+	}
+	_, _, acked, canceled := handler.counts()
+	assert.Equal(t, 2, acked, "In-flight messages are acked before Shutdown returns")
+	assert.Zero(t, canceled)
+	assert.ErrorIs(t, b.Publish(context.Background(), "jobs", bus.Message{}), bus.ErrClosed)
+}
+
+func TestMemoryBus_ShutdownStopsAtDeadline(t *testing.T) {
+	b := bus.NewMemoryBus()
+	handler := newBlockingHandler()
+
+	require.NoError(t, b.Subscribe("jobs", handler.handle))
+	require.NoError(t, publishN(b, "jobs", 1))
+	assert.Eventually(t, func() bool {
+		active, _, _, _ := handler.counts()
+		return active == 1
+	}, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := b.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the handler still running is canceled
+	assert.Eventually(t, func() bool {
+		_, _, _, canceled := handler.counts()
+		return canceled == 1
+	}, time.Second, 5*time.Millisecond)
+}
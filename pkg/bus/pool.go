@@ -0,0 +1,79 @@
+package bus
+
+import (
+	"context"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// channelPool hands out long-lived channels in confirm mode, so concurrent
+// publishers neither share one channel nor open a new one per message. At
+// most size channels are open at a time; get blocks when all are busy.
+type channelPool struct {
+	conn  *RabbitConnection
+	slots chan struct{}
+	idle  chan *amqp091.Channel
+}
+
+func newChannelPool(conn *RabbitConnection, size int) *channelPool {
+	return &channelPool{
+		conn:  conn,
+		slots: make(chan struct{}, size),
+		idle:  make(chan *amqp091.Channel, size),
+	}
+}
+
+// get returns an idle channel, opening one when none is left. The channel
+// must be given back with put.
+func (p *channelPool) get(ctx context.Context) (*amqp091.Channel, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for {
+		select {
+		case ch := <-p.idle:
+			if !ch.IsClosed() {
+				return ch, nil
+			}
+			continue
+		default:
+		}
+		break
+	}
+
+	ch, err := p.conn.Channel()
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	if err = ch.Confirm(false); err != nil {
+		ch.Close()
+		<-p.slots
+		return nil, err
+	}
+	return ch, nil
+}
+
+// put gives ch back; channels closed by the broker after an error are
+// dropped and replaced on demand.
+func (p *channelPool) put(ch *amqp091.Channel) {
+	if !ch.IsClosed() {
+		p.idle <- ch
+	}
+	<-p.slots
+}
+
+// reset closes the idle channels, which are useless after a reconnection.
+func (p *channelPool) reset() {
+	for {
+		select {
+		case ch := <-p.idle:
+			ch.Close()
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,588 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// RabbitBus maps Queue topics to durable queues on the default exchange and
+// Fanout topics to fanout exchanges, each subscriber getting its own
+// exclusive queue bound to the exchange.
+//
+// Deliveries are acknowledged once their handler succeeds. A failed message
+// of a topic with a RetryPolicy is republished to the delay queue
+// "<topic>.retry.<delay>", whose TTL dead-letters it back to the topic, and
+// to the durable "<topic>.dlq" queue once its attempts are exhausted.
+//
+// Messages are published on a small pool of long-lived channels in confirm
+// mode and Publish only returns once the broker confirmed the message. Every
+// topic is declared once, by Declare or on its first use.
+//
+// Each subscription runs as many workers as the concurrency of its topic and
+// the channel prefetch (QoS) is set to match, so the broker never hands a
+// consumer more messages than it can work on.
+//
+// When the connection is restored after an outage, the topology is declared
+// again and every subscription gets a new consumer. Publishing during the
+// outage fails with ErrDisconnected.
+type RabbitBus struct {
+	conn *RabbitConnection
+
+	mu            sync.Mutex
+	kinds         map[string]Kind
+	policies      map[string]RetryPolicy
+	workers       map[string]int
+	subscriptions []subscription
+	pool          *channelPool
+	consumers     []consumer
+	stopping      bool
+	running       sync.WaitGroup
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+// publishChannels is how many channels are used for publishing at most.
+const publishChannels = 4
+
+type subscription struct {
+	topic   string
+	handler Handler
+}
+
+// consumer is a subscription consuming on its own channel. The consumer tag
+// is the topic, which is unique on the channel.
+type consumer struct {
+	topic string
+	ch    *amqp091.Channel
+}
+
+// NewRabbitBus takes ownership of conn, which is closed by Close.
+func NewRabbitBus(conn *RabbitConnection) *RabbitBus {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &RabbitBus{
+		conn:     conn,
+		kinds:    make(map[string]Kind),
+		policies: make(map[string]RetryPolicy),
+		workers:  make(map[string]int),
+		pool:     newChannelPool(conn, publishChannels),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	conn.OnReconnect(b.restore)
+	return b
+}
+
+func (b *RabbitBus) Declare(topic string, kind Kind) error {
+	err := b.withChannel(func(ch *amqp091.Channel) error {
+		return declare(ch, topic, kind)
+	})
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.kinds[topic] = kind
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *RabbitBus) SetRetryPolicy(topic string, policy RetryPolicy) error {
+	if err := policy.validate(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	kind := b.kinds[topic]
+	b.mu.Unlock()
+	if kind == Fanout {
+		return fmt.Errorf("retries are only supported on queue topics, %s is a fanout", topic)
+	}
+
+	err := b.withChannel(func(ch *amqp091.Channel) error {
+		return declareRetry(ch, topic, policy)
+	})
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.kinds[topic] = Queue
+	b.policies[topic] = policy
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *RabbitBus) SetConcurrency(topic string, workers int) error {
+	if err := validateWorkers(workers); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.workers[topic] = workers
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *RabbitBus) Publish(ctx context.Context, topic string, msg Message) error {
+	b.mu.Lock()
+	kind, declared := b.kinds[topic]
+	b.mu.Unlock()
+
+	if !declared {
+		if err := b.Declare(topic, Queue); err != nil {
+			return err
+		}
+	}
+
+	if kind == Fanout {
+		return b.publish(ctx, topic, "", msg)
+	}
+	return b.publish(ctx, "", topic, msg)
+}
+
+func (b *RabbitBus) Subscribe(topic string, handler Handler) error {
+	if err := b.consume(topic, handler); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.subscriptions = append(b.subscriptions, subscription{topic: topic, handler: handler})
+	b.mu.Unlock()
+	return nil
+}
+
+// Status reports the state of the broker connection and how many consumers
+// are running on it.
+func (b *RabbitBus) Status() Status {
+	status := b.conn.Status()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.stopping {
+		for _, c := range b.consumers {
+			if !c.ch.IsClosed() {
+				status.Consumers++
+			}
+		}
+	}
+	return status
+}
+
+// restore declares the topology again and restarts the consumers once the
+// connection came back.
+func (b *RabbitBus) restore() {
+	b.pool.reset()
+
+	// the broker round-trips run on copies so that Publish and Status do
+	// not wait for them
+	b.mu.Lock()
+	b.consumers = nil
+	subscriptions := append([]subscription(nil), b.subscriptions...)
+	kinds := maps.Clone(b.kinds)
+	policies := maps.Clone(b.policies)
+	b.mu.Unlock()
+
+	err := b.withChannel(func(ch *amqp091.Channel) error {
+		for topic, kind := range kinds {
+			if err := declare(ch, topic, kind); err != nil {
+				return err
+			}
+		}
+		for topic, policy := range policies {
+			if err := declareRetry(ch, topic, policy); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error declaring the topology after reconnecting: %v", err)
+	}
+
+	for _, sub := range subscriptions {
+		if err := b.consume(sub.topic, sub.handler); err != nil {
+			log.Printf("Error restarting the consumer of %s: %v", sub.topic, err)
+		}
+	}
+}
+
+// consume starts a consumer of topic on a channel of its own, handled by
+// the topic's workers.
+func (b *RabbitBus) consume(topic string, handler Handler) error {
+	b.mu.Lock()
+	kind, stopping := b.kinds[topic], b.stopping
+	workers := max(b.workers[topic], 1)
+	b.mu.Unlock()
+	if stopping {
+		return ErrClosed
+	}
+
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return err
+	}
+	if err = declare(ch, topic, kind); err != nil {
+		return err
+	}
+	if err = ch.Qos(workers, 0, false); err != nil {
+		return err
+	}
+
+	queue := topic
+	exclusive := false
+	if kind == Fanout {
+		q, err := ch.QueueDeclare(
+			"",
+			false,
+			true,
+			true,
+			false,
+			nil,
+		)
+		if err != nil {
+			return err
+		}
+		if err = ch.QueueBind(q.Name, "", topic, false, nil); err != nil {
+			return err
+		}
+		queue = q.Name
+		exclusive = true
+	}
+
+	msgs, err := ch.Consume(
+		queue,
+		topic,
+		false,
+		exclusive,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.consumers = append(b.consumers, consumer{topic: topic, ch: ch})
+	b.running.Add(workers)
+	b.mu.Unlock()
+
+	// the deliveries channel is closed once the consumer is canceled and
+	// the prefetched deliveries were read, which ends every worker
+	for range workers {
+		go func() {
+			defer b.running.Done()
+			for d := range msgs {
+				msg := fromDelivery(d)
+				b.handle(topic, handler, d, msg)
+			}
+		}()
+	}
+	log.Printf("Consuming %s with %d workers", topic, workers)
+
+	return nil
+}
+
+// handle runs handler and settles the delivery: acked on success, moved to
+// a delay queue or the dead-letter queue on failure, or dropped when the
+// topic has no retry policy. It is requeued if moving it fails.
+func (b *RabbitBus) handle(topic string, handler Handler, d amqp091.Delivery, msg Message) {
+	err := handler(b.ctx, msg)
+	if err == nil {
+		b.settle(d.Ack(false))
+		return
+	}
+	log.Printf("Error handling message from %s: %v", topic, err)
+
+	b.mu.Lock()
+	policy, ok := b.policies[topic]
+	b.mu.Unlock()
+	if !ok {
+		b.settle(d.Nack(false, false))
+		return
+	}
+
+	attempt := Attempt(msg)
+	if attempt >= policy.MaxAttempts {
+		err = b.publishRaw(b.ctx, DeadLetterTopic(topic), deadLettered(msg, err))
+	} else {
+		err = b.publishRaw(b.ctx, delayQueue(topic, policy.Delay(attempt)), retried(msg, attempt))
+	}
+	if err != nil {
+		log.Printf("Error moving message from %s: %v", topic, err)
+		b.settle(d.Nack(false, true))
+		return
+	}
+	b.settle(d.Ack(false))
+}
+
+func (b *RabbitBus) settle(err error) {
+	if err != nil {
+		log.Printf("Error acknowledging message: %v", err)
+	}
+}
+
+// publishRaw publishes to an already declared queue on the default exchange.
+func (b *RabbitBus) publishRaw(ctx context.Context, queue string, msg Message) error {
+	return b.publish(ctx, "", queue, msg)
+}
+
+// publish sends msg on a pooled channel and waits for the broker to
+// confirm it.
+func (b *RabbitBus) publish(ctx context.Context, exchange, key string, msg Message) error {
+	ch, err := b.pool.get(ctx)
+	if err != nil {
+		return err
+	}
+	defer b.pool.put(ch)
+
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx,
+		exchange,
+		key,
+		false,
+		false,
+		amqp091.Publishing{
+			ContentType:   msg.ContentType,
+			CorrelationId: msg.CorrelationID,
+			ReplyTo:       msg.ReplyTo,
+			DeliveryMode:  amqp091.Persistent,
+			Headers:       toTable(msg.Headers),
+			Body:          msg.Body,
+		})
+	if err != nil {
+		return err
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return fmt.Errorf("message to %q was not confirmed by the broker", exchange+key)
+	}
+	return nil
+}
+
+// withChannel runs fn on a pooled channel, used for declaring topology.
+func (b *RabbitBus) withChannel(fn func(ch *amqp091.Channel) error) error {
+	ch, err := b.pool.get(b.ctx)
+	if err != nil {
+		return err
+	}
+	defer b.pool.put(ch)
+
+	return fn(ch)
+}
+
+func (b *RabbitBus) DeadLetters(ctx context.Context, topic string, limit int) ([]Message, error) {
+	ch, err := b.deadLetterChannel(topic)
+	if err != nil {
+		return nil, err
+	}
+	// closing the channel requeues every message we got without acking it
+	defer ch.Close()
+
+	var msgs []Message
+	for len(msgs) < limit {
+		d, ok, err := ch.Get(DeadLetterTopic(topic), false)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		msgs = append(msgs, fromDelivery(d))
+	}
+	return msgs, nil
+}
+
+func (b *RabbitBus) Replay(ctx context.Context, topic string, limit int) (int, error) {
+	ch, err := b.deadLetterChannel(topic)
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	replayedCount := 0
+	for replayedCount < limit {
+		d, ok, err := ch.Get(DeadLetterTopic(topic), false)
+		if err != nil {
+			return replayedCount, err
+		}
+		if !ok {
+			break
+		}
+
+		if err = b.Publish(ctx, topic, replayed(fromDelivery(d))); err != nil {
+			return replayedCount, errors.Join(err, d.Nack(false, true))
+		}
+		if err = d.Ack(false); err != nil {
+			return replayedCount, err
+		}
+		replayedCount++
+	}
+	return replayedCount, nil
+}
+
+// deadLetterChannel opens a channel for reading the dead letters of topic.
+func (b *RabbitBus) deadLetterChannel(topic string) (*amqp091.Channel, error) {
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	if err = declare(ch, DeadLetterTopic(topic), Queue); err != nil {
+		ch.Close()
+		return nil, err
+	}
+	return ch, nil
+}
+
+// Shutdown cancels the consumers so the broker stops delivering, lets the
+// workers finish the deliveries they already received and closes the bus.
+// Deliveries still unacknowledged when ctx is done are requeued by the
+// broker as their channels close.
+func (b *RabbitBus) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	b.stopping = true
+	consumers := append([]consumer(nil), b.consumers...)
+	b.mu.Unlock()
+
+	for _, c := range consumers {
+		if err := c.ch.Cancel(c.topic, false); err != nil && !c.ch.IsClosed() {
+			log.Printf("Error canceling the consumer of %s: %v", c.topic, err)
+		}
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		b.running.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = fmt.Errorf("draining the consumers: %w", ctx.Err())
+	}
+	return errors.Join(err, b.Close())
+}
+
+func (b *RabbitBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.stopping = true
+	b.cancel()
+	for _, c := range b.consumers {
+		c.ch.Close()
+	}
+	b.pool.reset()
+	return b.conn.Close()
+}
+
+func declare(ch *amqp091.Channel, topic string, kind Kind) error {
+	if kind == Fanout {
+		return ch.ExchangeDeclare(
+			topic,
+			amqp091.ExchangeFanout,
+			true,
+			false,
+			false,
+			false,
+			nil,
+		)
+	}
+
+	_, err := ch.QueueDeclare(
+		topic,
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+	return err
+}
+
+func delayQueue(topic string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", topic, delay)
+}
+
+// declareRetry declares the dead-letter and delay queues of topic.
+func declareRetry(ch *amqp091.Channel, topic string, policy RetryPolicy) error {
+	if err := declare(ch, topic, Queue); err != nil {
+		return err
+	}
+	if err := declare(ch, DeadLetterTopic(topic), Queue); err != nil {
+		return err
+	}
+	for attempt := 1; attempt < policy.MaxAttempts; attempt++ {
+		if err := declareDelay(ch, topic, policy.Delay(attempt)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// declareDelay declares the queue holding the messages of topic for delay
+// before dead-lettering them back to it.
+func declareDelay(ch *amqp091.Channel, topic string, delay time.Duration) error {
+	_, err := ch.QueueDeclare(
+		delayQueue(topic, delay),
+		true,
+		false,
+		false,
+		false,
+		amqp091.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": topic,
+		},
+	)
+	return err
+}
+
+func fromDelivery(d amqp091.Delivery) Message {
+	return Message{
+		ContentType:   d.ContentType,
+		CorrelationID: d.CorrelationId,
+		ReplyTo:       d.ReplyTo,
+		Headers:       fromTable(d.Headers),
+		Body:          d.Body,
+	}
+}
+
+func toTable(headers map[string]string) amqp091.Table {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	table := make(amqp091.Table, len(headers))
+	for k, v := range headers {
+		table[k] = v
+	}
+	return table
+}
+
+func fromTable(table amqp091.Table) map[string]string {
+	if len(table) == 0 {
+		return nil
+	}
+
+	headers := make(map[string]string, len(table))
+	for k, v := range table {
+		if s, ok := v.(string); ok {
+			headers[k] = s
+		}
+	}
+	return headers
+}